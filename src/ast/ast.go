@@ -1,19 +1,59 @@
 package ast
 
-import "custom_parser/src/helpers"
+import (
+	"custom_parser/src/helpers"
+	"custom_parser/src/lexer"
+	"strings"
+)
 
+// Every node reports the source range it was parsed from through Span.
+
+// Statements, and the members of classes and structs, also carry the
+// comments attached to them by the parser.
 type Stmt interface {
 	stmt()
+	Span() lexer.Span
+	Attached() Comments
+	WithComments(comments Comments) Stmt
 }
 
+// Expressions can be given a wider span, so that a parenthesised
+// expression covers its parentheses.
 type Expr interface {
 	expr()
+	Span() lexer.Span
+	WithSpan(span lexer.Span) Expr
 }
 
 type Type interface {
 	_type()
+	Span() lexer.Span
+}
+
+// Comment is a comment the lexer kept as trivia. Text includes the // or
+// /* */ markers.
+type Comment struct {
+	Text string
+	Loc  lexer.Span
+}
+
+// IsDoc reports whether c is a /// doc comment.
+func (c Comment) IsDoc() bool {
+	return strings.HasPrefix(c.Text, "///") && !strings.HasPrefix(c.Text, "////")
+}
+
+// Comments are embedded in every node comments can be attached to. Leading
+// holds the comments between the previous node and this one. Trailing holds
+// the comment on the same line after the node, along with any comment
+// inside the node that no nested statement claimed, such as one in an
+// empty block.
+type Comments struct {
+	Leading  []Comment
+	Trailing []Comment
 }
 
+func (c Comments) Attached() Comments { return c }
+
 func ExpectExpr[T Expr](expr Expr) T {
 	return helpers.ExpectType[T](expr)
 }
@@ -5,23 +5,86 @@ import "custom_parser/src/lexer"
 // ---------
 // Literals
 // ---------
+// NumberExpr is an int literal when Integral, with its exact value in Int,
+// and a float literal otherwise.
 type NumberExpr struct {
-	Value float64
+	Value    float64
+	Int      int64
+	Integral bool   // written without a fraction or exponent
+	Raw      string // as written, e.g. 0xFF or 1_000
+	Loc      lexer.Span
 }
 
-func (n NumberExpr) expr() {}
+func (n NumberExpr) expr()            {}
+func (n NumberExpr) Span() lexer.Span { return n.Loc }
+func (n NumberExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type StringExpr struct {
-	Value string
+	Value string // with escapes decoded
+	Raw   string // as written, quotes included
+	Loc   lexer.Span
+}
+
+func (n StringExpr) expr()            {}
+func (n StringExpr) Span() lexer.Span { return n.Loc }
+func (n StringExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
+
+// TemplateExpr is a backtick string such as `Hello ${name}`. Its parts
+// alternate between literal text, held in a StringExpr whose Raw omits the
+// quotes, and the expressions interpolated with ${...}: even indices are
+// text and odd ones expressions, with text at both ends.
+type TemplateExpr struct {
+	Parts []Expr
+	Loc   lexer.Span
+}
+
+func (n TemplateExpr) expr()            {}
+func (n TemplateExpr) Span() lexer.Span { return n.Loc }
+func (n TemplateExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
 }
 
-func (n StringExpr) expr() {}
+type BooleanExpr struct {
+	Value bool
+	Loc   lexer.Span
+}
+
+func (n BooleanExpr) expr()            {}
+func (n BooleanExpr) Span() lexer.Span { return n.Loc }
+func (n BooleanExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
+
+type NullExpr struct {
+	Loc lexer.Span
+}
+
+func (n NullExpr) expr()            {}
+func (n NullExpr) Span() lexer.Span { return n.Loc }
+func (n NullExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type SymbolExpr struct {
 	Value string
+	Loc   lexer.Span
 }
 
-func (n SymbolExpr) expr() {}
+func (n SymbolExpr) expr()            {}
+func (n SymbolExpr) Span() lexer.Span { return n.Loc }
+func (n SymbolExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 // ---------
 // Complex expressions
@@ -32,9 +95,15 @@ type BinaryExpr struct {
 	Left     Expr
 	Operator lexer.Token
 	Right    Expr
+	Loc      lexer.Span
 }
 
-func (n BinaryExpr) expr() {}
+func (n BinaryExpr) expr()            {}
+func (n BinaryExpr) Span() lexer.Span { return n.Loc }
+func (n BinaryExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 // examples:
 // a = a + 5;
@@ -44,75 +113,162 @@ type AssignmentExpr struct {
 	Assignee Expr
 	Operator lexer.Token
 	Value    Expr
+	Loc      lexer.Span
 }
 
-func (n AssignmentExpr) expr() {}
+func (n AssignmentExpr) expr()            {}
+func (n AssignmentExpr) Span() lexer.Span { return n.Loc }
+func (n AssignmentExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type PrefixExpr struct {
 	Operator  lexer.Token
 	RightExpr Expr
+	Loc       lexer.Span
 }
 
-func (n PrefixExpr) expr() {}
+func (n PrefixExpr) expr()            {}
+func (n PrefixExpr) Span() lexer.Span { return n.Loc }
+func (n PrefixExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type MemberExpr struct {
 	Member   Expr
 	Property string
+	Loc      lexer.Span
 }
 
-func (n MemberExpr) expr() {}
+func (n MemberExpr) expr()            {}
+func (n MemberExpr) Span() lexer.Span { return n.Loc }
+func (n MemberExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type CallExpr struct {
 	Method    Expr
 	Arguments []Expr
+	Loc       lexer.Span
 }
 
-func (n CallExpr) expr() {}
+func (n CallExpr) expr()            {}
+func (n CallExpr) Span() lexer.Span { return n.Loc }
+func (n CallExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type ComputedExpr struct {
 	Member   Expr
 	Property Expr
+	Loc      lexer.Span
+}
+
+func (n ComputedExpr) expr()            {}
+func (n ComputedExpr) Span() lexer.Span { return n.Loc }
+func (n ComputedExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
+
+// ConditionalExpr is Condition ? Consequent : Alternate, which evaluates
+// only the branch that Condition selects.
+type ConditionalExpr struct {
+	Condition  Expr
+	Consequent Expr
+	Alternate  Expr
+	Loc        lexer.Span
 }
 
-func (n ComputedExpr) expr() {}
+func (n ConditionalExpr) expr()            {}
+func (n ConditionalExpr) Span() lexer.Span { return n.Loc }
+func (n ConditionalExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
+// RangeExpr is Lower..Upper, which leaves Upper out, or Lower..=Upper,
+// which includes it, counting in steps of Step, or of 1 when Step is nil. A
+// negative step counts down.
 type RangeExpr struct {
-	Lower Expr
-	Upper Expr
+	Lower     Expr
+	Upper     Expr
+	Step      Expr
+	Inclusive bool
+	Loc       lexer.Span
 }
 
-func (n RangeExpr) expr() {}
+func (n RangeExpr) expr()            {}
+func (n RangeExpr) Span() lexer.Span { return n.Loc }
+func (n RangeExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type FunctionExpr struct {
 	Parameters []Parameter
 	Body       []Stmt
 	ReturnType Type
+	Loc        lexer.Span
 }
 
-func (n FunctionExpr) expr() {}
+func (n FunctionExpr) expr()            {}
+func (n FunctionExpr) Span() lexer.Span { return n.Loc }
+func (n FunctionExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type NewExpr struct {
 	Instantiation CallExpr
+	Loc           lexer.Span
 }
 
-func (n NewExpr) expr() {}
+func (n NewExpr) expr()            {}
+func (n NewExpr) Span() lexer.Span { return n.Loc }
+func (n NewExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type ArrayLiteral struct {
 	Contents []Expr
+	Loc      lexer.Span
 }
 
-func (n ArrayLiteral) expr() {}
+func (n ArrayLiteral) expr()            {}
+func (n ArrayLiteral) Span() lexer.Span { return n.Loc }
+func (n ArrayLiteral) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type StructInstantiationExpr struct {
 	StructName string
 	Properties map[string]Expr
+	Loc        lexer.Span
 }
 
-func (n StructInstantiationExpr) expr() {}
+func (n StructInstantiationExpr) expr()            {}
+func (n StructInstantiationExpr) Span() lexer.Span { return n.Loc }
+func (n StructInstantiationExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
 
 type ArrayInstantiationExpr struct {
 	Underlying Type
 	Contents   []Expr
+	Loc        lexer.Span
 }
 
-func (n ArrayInstantiationExpr) expr() {}
+func (n ArrayInstantiationExpr) expr()            {}
+func (n ArrayInstantiationExpr) Span() lexer.Span { return n.Loc }
+func (n ArrayInstantiationExpr) WithSpan(span lexer.Span) Expr {
+	n.Loc = span
+	return n
+}
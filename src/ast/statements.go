@@ -1,85 +1,288 @@
 package ast
 
+import "custom_parser/src/lexer"
+
 type BlockStmt struct {
+	Comments
 	Body []Stmt
+	Loc  lexer.Span
+}
+
+func (n BlockStmt) stmt()            {}
+func (n BlockStmt) Span() lexer.Span { return n.Loc }
+func (n BlockStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
 }
 
-func (n BlockStmt) stmt() {}
+// BadStmt stands in for source the parser skipped while recovering from a
+// syntax error.
+type BadStmt struct {
+	Comments
+	Loc lexer.Span
+}
+
+func (n BadStmt) stmt()            {}
+func (n BadStmt) Span() lexer.Span { return n.Loc }
+func (n BadStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type ExpressionStmt struct {
+	Comments
 	Expression Expr
+	Loc        lexer.Span
 }
 
-func (n ExpressionStmt) stmt() {}
+func (n ExpressionStmt) stmt()            {}
+func (n ExpressionStmt) Span() lexer.Span { return n.Loc }
+func (n ExpressionStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type VarDeclStmt struct {
+	Comments
+	Doc           string // text of the /// comments directly above
 	VariableName  string
 	IsConstant    bool
 	AssignedValue Expr
 	ExplicitType  Type
+	Loc           lexer.Span
 }
 
-func (n VarDeclStmt) stmt() {}
+func (n VarDeclStmt) stmt()            {}
+func (n VarDeclStmt) Span() lexer.Span { return n.Loc }
+func (n VarDeclStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type StructProperty struct {
+	Comments
 	IsStatic bool // is property static?
 	Type     Type
+	Loc      lexer.Span
 }
 
 type StructMethod struct {
-	IsStatic bool // is property static?
-	//Type     FnType
+	Comments
+	IsStatic   bool // is method static?
+	Parameters []Parameter
+	ReturnType Type
+	Body       []Stmt
+	Loc        lexer.Span
 }
 
 type StructDeclStmt struct {
+	Comments
+	Doc        string // text of the /// comments directly above
 	StructName string
 	Properties map[string]StructProperty
 	Methods    map[string]StructMethod
+	Loc        lexer.Span
 }
 
-func (n StructDeclStmt) stmt() {}
+func (n StructDeclStmt) stmt()            {}
+func (n StructDeclStmt) Span() lexer.Span { return n.Loc }
+func (n StructDeclStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+type ClassField struct {
+	Comments
+	Name         string
+	IsStatic     bool
+	IsConstant   bool
+	Type         Type // nil when inferred from DefaultValue
+	DefaultValue Expr // nil when the field starts out null
+	Loc          lexer.Span
+}
+
+type ClassMethod struct {
+	Comments
+	Name       string
+	IsStatic   bool
+	Parameters []Parameter
+	ReturnType Type
+	Body       []Stmt
+	Loc        lexer.Span
+}
 
+// ClassDeclarationStmt keeps fields and methods in declaration order. The
+// method named mount is the constructor; it is held in Constructor rather
+// than Methods. new runs it with the given arguments, which must match its
+// parameters.
 type ClassDeclarationStmt struct {
-	Name string
-	Body []Stmt
+	Comments
+	Doc         string // text of the /// comments directly above
+	Name        string
+	Fields      []ClassField
+	Methods     []ClassMethod
+	Constructor *ClassMethod
+	Loc         lexer.Span
 }
 
-func (n ClassDeclarationStmt) stmt() {}
+func (n ClassDeclarationStmt) stmt()            {}
+func (n ClassDeclarationStmt) Span() lexer.Span { return n.Loc }
+func (n ClassDeclarationStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type Parameter struct {
 	Name string
 	Type Type
+	Loc  lexer.Span
 }
 
 type FunctionDeclStmt struct {
+	Comments
+	Doc        string // text of the /// comments directly above
 	Parameters []Parameter
 	Name       string
 	Body       []Stmt
 	ReturnType Type
+	Loc        lexer.Span
 }
 
-func (n FunctionDeclStmt) stmt() {}
+func (n FunctionDeclStmt) stmt()            {}
+func (n FunctionDeclStmt) Span() lexer.Span { return n.Loc }
+func (n FunctionDeclStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type IfStmt struct {
+	Comments
 	Condition  Expr
 	Consequent Stmt
 	Alternate  Stmt
+	Loc        lexer.Span
 }
 
-func (n IfStmt) stmt() {}
+func (n IfStmt) stmt()            {}
+func (n IfStmt) Span() lexer.Span { return n.Loc }
+func (n IfStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
 
 type ImportStmt struct {
+	Comments
 	Name string
 	From string
+	Loc  lexer.Span
+}
+
+func (n ImportStmt) stmt()            {}
+func (n ImportStmt) Span() lexer.Span { return n.Loc }
+func (n ImportStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+// ReturnStmt ends the enclosing function. Value is nil for a bare return,
+// which returns null.
+type ReturnStmt struct {
+	Comments
+	Value Expr
+	Loc   lexer.Span
+}
+
+func (n ReturnStmt) stmt()            {}
+func (n ReturnStmt) Span() lexer.Span { return n.Loc }
+func (n ReturnStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+// WhileStmt runs Body for as long as Condition holds. Label is the name
+// given to the loop for break and continue, or "".
+type WhileStmt struct {
+	Comments
+	Label     string
+	Condition Expr
+	Body      []Stmt
+	Loc       lexer.Span
+}
+
+func (n WhileStmt) stmt()            {}
+func (n WhileStmt) Span() lexer.Span { return n.Loc }
+func (n WhileStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
 }
 
-func (n ImportStmt) stmt() {}
+// ForStmt is a C-style loop. Init, Condition and Post are each nil when
+// left out; a missing Condition loops until a break.
+type ForStmt struct {
+	Comments
+	Label     string
+	Init      Stmt // a VarDeclStmt or an ExpressionStmt, scoped to the loop
+	Condition Expr
+	Post      Expr
+	Body      []Stmt
+	Loc       lexer.Span
+}
+
+func (n ForStmt) stmt()            {}
+func (n ForStmt) Span() lexer.Span { return n.Loc }
+func (n ForStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+// BreakStmt leaves the innermost loop, or the enclosing loop named Label.
+type BreakStmt struct {
+	Comments
+	Label string
+	Loc   lexer.Span
+}
+
+func (n BreakStmt) stmt()            {}
+func (n BreakStmt) Span() lexer.Span { return n.Loc }
+func (n BreakStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+// ContinueStmt starts the next iteration of the innermost loop, or of the
+// enclosing loop named Label.
+type ContinueStmt struct {
+	Comments
+	Label string
+	Loc   lexer.Span
+}
 
+func (n ContinueStmt) stmt()            {}
+func (n ContinueStmt) Span() lexer.Span { return n.Loc }
+func (n ContinueStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
+
+// ForeachStmt binds Value to each element of Iterable and, when Index is
+// not "", Index to the element's position. A map binds Value to each key
+// and Index to the value stored under it instead, as in
+// `foreach key, value in m`.
 type ForeachStmt struct {
+	Comments
+	Label    string
 	Value    string
-	Index    bool
+	ValueLoc lexer.Span
+	Index    string
+	IndexLoc lexer.Span
 	Iterable Expr
 	Body     []Stmt
+	Loc      lexer.Span
 }
 
-func (n ForeachStmt) stmt() {}
+func (n ForeachStmt) stmt()            {}
+func (n ForeachStmt) Span() lexer.Span { return n.Loc }
+func (n ForeachStmt) WithComments(comments Comments) Stmt {
+	n.Comments = comments
+	return n
+}
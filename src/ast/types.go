@@ -1,13 +1,19 @@
 package ast
 
+import "custom_parser/src/lexer"
+
 type SymbolType struct {
 	Name string // T
+	Loc  lexer.Span
 }
 
-func (t SymbolType) _type() {}
+func (t SymbolType) _type()           {}
+func (t SymbolType) Span() lexer.Span { return t.Loc }
 
 type ArrayType struct {
 	Underlying Type // []T
+	Loc        lexer.Span
 }
 
-func (t ArrayType) _type() {}
+func (t ArrayType) _type()           {}
+func (t ArrayType) Span() lexer.Span { return t.Loc }
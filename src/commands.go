@@ -0,0 +1,234 @@
+package main
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/format"
+	"custom_parser/src/interpreter"
+	"custom_parser/src/lexer"
+	"custom_parser/src/parser"
+	"custom_parser/src/typecheck"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sanity-io/litter"
+)
+
+type source struct {
+	name string
+	text string
+}
+
+// readSources loads every named file, or standard input when there are no
+// names or the name is "-".
+func readSources(names []string, stdin io.Reader) ([]source, error) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	sources := make([]source, 0, len(names))
+	for _, name := range names {
+		var bytes []byte
+		var err error
+		if name == "-" {
+			name = "<stdin>"
+			bytes, err = io.ReadAll(stdin)
+		} else {
+			bytes, err = os.ReadFile(name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, source{name: name, text: string(bytes)})
+	}
+
+	return sources, nil
+}
+
+// parseSource lexes and parses src, returning every diagnostic from both
+// stages.
+func parseSource(src source, mode lexer.Mode) (ast.BlockStmt, []diagnostics.Diagnostic) {
+	tokens, lexErrors := lexer.TokenizeMode(src.name, src.text, mode)
+	program, parseDiagnostics := parser.Parse(tokens)
+	return program, append(diagnostics.FromLexErrors(lexErrors), parseDiagnostics...)
+}
+
+func (c *cli) report(list []diagnostics.Diagnostic) {
+	for _, diagnostic := range list {
+		fmt.Fprintln(c.stderr, diagnostic)
+	}
+}
+
+func tokensCommand(c *cli, sources []source) int {
+	status := exitOK
+	for _, src := range sources {
+		tokens, lexErrors := lexer.TokenizeMode(src.name, src.text, c.lexerMode())
+		for _, token := range tokens {
+			fmt.Fprintf(c.stdout, "%s\t%s\t%q\n", token.Span.Start, lexer.TokenKindString(token.Kind), token.Value)
+		}
+
+		if len(lexErrors) > 0 {
+			c.report(diagnostics.FromLexErrors(lexErrors))
+			status = exitErrors
+		}
+	}
+
+	return status
+}
+
+// lexerMode is the lexer mode selected by -comments.
+func (c *cli) lexerMode() lexer.Mode {
+	if c.comments {
+		return lexer.KeepComments
+	}
+
+	return 0
+}
+
+func tokensFlags(fs *flag.FlagSet, c *cli) {
+	fs.BoolVar(&c.comments, "comments", false, "include comment tokens")
+}
+
+func astFlags(fs *flag.FlagSet, c *cli) {
+	fs.BoolVar(&c.showSpans, "spans", false, "include the source span of every node")
+	fs.BoolVar(&c.comments, "comments", false, "include the comments attached to each node")
+}
+
+func astCommand(c *cli, sources []source) int {
+	status := exitOK
+	for _, src := range sources {
+		program, list := parseSource(src, c.lexerMode())
+		if len(list) > 0 {
+			c.report(list)
+			status = exitErrors
+		}
+
+		excluded := []string{}
+		if !c.showSpans {
+			excluded = append(excluded, "Loc", "Span")
+		}
+
+		if !c.comments {
+			excluded = append(excluded, "Comments")
+		}
+
+		options := litter.Config
+		if len(excluded) > 0 {
+			options.FieldExclusions = regexp.MustCompile(`^(` + strings.Join(excluded, "|") + `)$`)
+		}
+
+		fmt.Fprintln(c.stdout, options.Sdump(program))
+	}
+
+	return status
+}
+
+func checkCommand(c *cli, sources []source) int {
+	status := exitOK
+	for _, src := range sources {
+		program, list := parseSource(src, 0)
+		list = append(list, typecheck.Check(program)...)
+		if diagnostics.HasErrors(list) {
+			status = exitErrors
+		}
+
+		c.report(list)
+	}
+
+	return status
+}
+
+func runFlags(fs *flag.FlagSet, c *cli) {
+	fs.BoolVar(&c.typecheck, "check", false, "type check each file before running it")
+}
+
+// runCommand executes each file in its own interpreter. A file with syntax
+// errors, or type errors when -check is given, is not run, but the files
+// after it still are.
+func runCommand(c *cli, sources []source) int {
+	status := exitOK
+	for _, src := range sources {
+		program, list := parseSource(src, 0)
+		if c.typecheck && !diagnostics.HasErrors(list) {
+			list = append(list, typecheck.Check(program)...)
+		}
+
+		c.report(list)
+		if diagnostics.HasErrors(list) {
+			status = exitErrors
+			continue
+		}
+
+		if err := interpreter.New(c.stdout).Run(program); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			status = exitErrors
+		}
+	}
+
+	return status
+}
+
+func fmtFlags(fs *flag.FlagSet, c *cli) {
+	fs.BoolVar(&c.write, "w", false, "write the result back to the file instead of printing it")
+	fs.BoolVar(&c.diff, "d", false, "print a diff of the changes instead of the result")
+	fs.BoolVar(&c.list, "l", false, "list the files whose formatting would change")
+}
+
+// fmtCommand formats each file. Files with syntax errors are reported and
+// left untouched, as are files that cannot be rewritten; either way the
+// remaining files are still formatted.
+func fmtCommand(c *cli, sources []source) int {
+	status := exitOK
+	for _, src := range sources {
+		formatted, list := format.Source(src.name, src.text)
+		c.report(list)
+		if diagnostics.HasErrors(list) {
+			status = max(status, exitErrors)
+			continue
+		}
+
+		changed := formatted != src.text
+		if c.list && changed {
+			fmt.Fprintln(c.stdout, src.name)
+		}
+
+		if c.diff {
+			fmt.Fprint(c.stdout, unifiedDiff(src.name, src.text, formatted))
+		}
+
+		if c.write && changed {
+			if err := rewrite(src.name, formatted); err != nil {
+				fmt.Fprintln(c.stderr, err)
+				status = exitFailure
+			}
+		}
+
+		if !c.list && !c.diff && !c.write {
+			fmt.Fprint(c.stdout, formatted)
+		}
+	}
+
+	return status
+}
+
+// rewrite replaces the contents of the file name with text, keeping its
+// permissions.
+func rewrite(name, text string) error {
+	if name == "<stdin>" {
+		return errors.New("cannot use -w with standard input")
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, []byte(text), info.Mode().Perm())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunContinuesAfterFailingFile(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct{ name, src string }{
+		{"syntax.lang", "let a = ;"},
+		{"runtime.lang", "println(1 / 0);"},
+		{"ok.lang", `println("ran");`},
+	}
+
+	var names []string
+	for _, file := range files {
+		name := filepath.Join(dir, file.name)
+		if err := os.WriteFile(name, []byte(file.src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, name)
+	}
+
+	var stdout, stderr strings.Builder
+	status := runCLI(append([]string{"run"}, names...), strings.NewReader(""), &stdout, &stderr)
+	if status != exitErrors {
+		t.Errorf("status %d, want %d", status, exitErrors)
+	}
+
+	if stdout.String() != "ran\n" {
+		t.Errorf("stdout %q, want the last file to run", stdout.String())
+	}
+
+	for _, want := range []string{"syntax.lang:1:9", "runtime.lang:1:9: runtime error: division by zero"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr does not mention %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestFmtWriteContinuesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.lang")
+	broken := filepath.Join(dir, "broken.lang")
+	if err := os.WriteFile(script, []byte("let a=1;"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(broken, []byte("let a = ;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	status := runCLI([]string{"fmt", "-w", "-", script, broken}, strings.NewReader("let b=2;"), &stdout, &stderr)
+	if status != exitFailure {
+		t.Errorf("status %d, want %d", status, exitFailure)
+	}
+
+	if !strings.Contains(stderr.String(), "cannot use -w with standard input") {
+		t.Errorf("stderr does not mention standard input:\n%s", stderr.String())
+	}
+
+	// The file after standard input is still rewritten, keeping its mode
+	src, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(src) != "let a = 1;\n" {
+		t.Errorf("got %q, want the file formatted", src)
+	}
+
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("got mode %v, want 0755 kept", info.Mode().Perm())
+	}
+}
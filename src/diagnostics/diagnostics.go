@@ -0,0 +1,132 @@
+package diagnostics
+
+import (
+	"custom_parser/src/lexer"
+	"fmt"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	default:
+		return fmt.Sprintf("severity(%d)", s)
+	}
+}
+
+// Code identifies a class of diagnostic so tools can match on it without
+// scraping the message.
+type Code string
+
+// Lexer codes
+const (
+	UnexpectedCharacter Code = "L0001"
+	UnterminatedString  Code = "L0002"
+	UnterminatedComment Code = "L0003"
+	InvalidEscape       Code = "L0004"
+	InvalidNumber       Code = "L0005"
+	InvalidUTF8         Code = "L0006"
+)
+
+// Parser codes
+const (
+	UnexpectedToken      Code = "P0001" // a specific token kind was required
+	ExpectedExpression   Code = "P0002" // no nud handler for the current token
+	UnexpectedOperator   Code = "P0003" // binding power but no led handler
+	ExpectedType         Code = "P0004" // no type nud handler for the current token
+	MissingInitializer   Code = "P0005" // let without type or value
+	ConstWithoutValue    Code = "P0006"
+	DuplicateProperty    Code = "P0007"
+	InvalidStructMember  Code = "P0008"
+	InvalidInstantiation Code = "P0009" // new applied to something other than a call
+	InvalidClassMember   Code = "P0010"
+	NumberOutOfRange     Code = "P0011"
+	InvalidReturn        Code = "P0012" // return outside of a function
+	InvalidBreak         Code = "P0013" // break or continue outside of the loop it names
+	InvalidLabel         Code = "P0014" // a label on something other than a loop
+)
+
+// Type checker codes
+const (
+	UnknownType       Code = "T0001"
+	UndefinedSymbol   Code = "T0002"
+	TypeMismatch      Code = "T0003"
+	InvalidOperands   Code = "T0004"
+	NotCallable       Code = "T0005"
+	ArgumentCount     Code = "T0006"
+	AssignToConstant  Code = "T0007"
+	Redeclared        Code = "T0008"
+	UnknownMember     Code = "T0009"
+	NotIterable       Code = "T0010"
+	InvalidAssignment Code = "T0011"
+	MissingReturn     Code = "T0012" // a function with a return type can finish without a value
+)
+
+type Diagnostic struct {
+	Code     Code
+	Severity Severity
+	Message  string
+	Span     lexer.Span
+
+	// Expected and Found are only set for diagnostics raised while the
+	// parser was looking for particular tokens.
+	Expected []lexer.TokenKind
+	Found    lexer.TokenKind
+}
+
+// Error renders the diagnostic as "file:line:col: severity[code]: message".
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Span.Start, d.Severity, d.Code, d.Message)
+}
+
+func (d Diagnostic) String() string {
+	return d.Error()
+}
+
+// FromLexErrors converts the errors returned by lexer.Tokenize.
+func FromLexErrors(errors []lexer.Error) []Diagnostic {
+	list := make([]Diagnostic, 0, len(errors))
+	for _, err := range errors {
+		list = append(list, Diagnostic{
+			Code:     lexCodes[err.Kind],
+			Severity: Error,
+			Message:  err.Message,
+			Span:     err.Span,
+			Found:    lexer.ILLEGAL,
+		})
+	}
+
+	return list
+}
+
+var lexCodes = map[lexer.ErrorKind]Code{
+	lexer.UnexpectedCharacter: UnexpectedCharacter,
+	lexer.UnterminatedString:  UnterminatedString,
+	lexer.UnterminatedComment: UnterminatedComment,
+	lexer.InvalidEscape:       InvalidEscape,
+	lexer.InvalidNumber:       InvalidNumber,
+	lexer.InvalidUTF8:         InvalidUTF8,
+}
+
+// HasErrors reports whether any diagnostic in list has Error severity.
+func HasErrors(list []Diagnostic) bool {
+	for _, d := range list {
+		if d.Severity == Error {
+			return true
+		}
+	}
+
+	return false
+}
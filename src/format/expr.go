@@ -0,0 +1,298 @@
+package format
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/parser"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	assignmentPrecedence  = parser.AssignmentPrecedence
+	conditionalPrecedence = parser.ConditionalPrecedence
+	rangePrecedence       = parser.RangePrecedence
+	unaryPrecedence       = parser.UnaryPrecedence
+	primaryPrecedence     = parser.PrimaryPrecedence
+)
+
+// precedence is the binding power of the operator at the root of expr.
+// Calls, member accesses and literals bind tightest of all.
+func precedence(expr ast.Expr) int {
+	switch expr := expr.(type) {
+	case ast.BinaryExpr:
+		return parser.InfixPrecedence(expr.Operator.Kind)
+	case ast.AssignmentExpr:
+		return assignmentPrecedence
+	case ast.ConditionalExpr:
+		return conditionalPrecedence
+	case ast.RangeExpr:
+		return rangePrecedence
+	case ast.PrefixExpr:
+		return unaryPrecedence
+	default:
+		return primaryPrecedence
+	}
+}
+
+// operand prints expr where it is parsed with binding power bp, adding
+// parentheses when expr binds more loosely. strict also parenthesises an
+// operator binding exactly as tightly, as on the right of a left
+// associative operator.
+func (p *printer) operand(expr ast.Expr, bp int, strict bool) {
+	prec := precedence(expr)
+	if prec > bp || prec == bp && !strict {
+		p.expr(expr)
+		return
+	}
+
+	p.nested(func() {
+		p.write("(")
+		p.expr(expr)
+		p.write(")")
+	})
+}
+
+// callee prints what is called, indexed or has a member taken. A function
+// there is parenthesised, so that it reads plainly and is not taken for a
+// declaration at the start of a statement.
+func (p *printer) callee(expr ast.Expr) {
+	if _, ok := expr.(ast.FunctionExpr); ok {
+		p.operand(expr, primaryPrecedence, true)
+		return
+	}
+
+	p.operand(expr, primaryPrecedence, false)
+}
+
+// startsWithFunction reports whether expr is printed starting with a
+// function expression that callee leaves bare, which an expression
+// statement has to parenthesise to keep it from reading as a declaration.
+func startsWithFunction(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case ast.FunctionExpr:
+		return true
+	case ast.BinaryExpr:
+		return startsWithFunction(expr.Left)
+	case ast.AssignmentExpr:
+		return startsWithFunction(expr.Assignee)
+	case ast.ConditionalExpr:
+		return startsWithFunction(expr.Condition)
+	case ast.RangeExpr:
+		return startsWithFunction(expr.Lower)
+	default:
+		return false
+	}
+}
+
+// header prints the condition of an if or the iterable of a foreach, where
+// a struct literal would be taken for the start of the block.
+func (p *printer) header(expr ast.Expr, bp int) {
+	p.noStructLiterals = true
+	p.operand(expr, bp, true)
+	p.noStructLiterals = false
+}
+
+// nested prints inside brackets, where struct literals are allowed again.
+func (p *printer) nested(print func()) {
+	noStructLiterals := p.noStructLiterals
+	p.noStructLiterals = false
+	print()
+	p.noStructLiterals = noStructLiterals
+}
+
+// exprList prints a comma separated list whose elements the parser reads
+// with binding power bp.
+func (p *printer) exprList(exprs []ast.Expr, bp int) {
+	p.nested(func() {
+		for i, expr := range exprs {
+			if i > 0 {
+				p.write(", ")
+			}
+
+			p.operand(expr, bp, true)
+		}
+	})
+}
+
+func (p *printer) expr(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case ast.NumberExpr:
+		if expr.Raw != "" {
+			p.write(expr.Raw)
+		} else {
+			p.write(strconv.FormatFloat(expr.Value, 'f', -1, 64))
+		}
+	case ast.StringExpr:
+		if expr.Raw != "" {
+			p.write(expr.Raw)
+		} else {
+			p.write(quote(expr.Value))
+		}
+	case ast.TemplateExpr:
+		p.write("`")
+		for i, part := range expr.Parts {
+			if i%2 == 0 {
+				p.write(part.(ast.StringExpr).Raw)
+				continue
+			}
+
+			p.write("${")
+			p.nested(func() { p.expr(part) })
+			p.write("}")
+		}
+
+		p.write("`")
+	case ast.BooleanExpr:
+		p.write(strconv.FormatBool(expr.Value))
+	case ast.NullExpr:
+		p.write("null")
+	case ast.SymbolExpr:
+		p.write(expr.Value)
+	case ast.BinaryExpr:
+		prec := precedence(expr)
+		p.operand(expr.Left, prec, false)
+		p.write(" ", expr.Operator.Value, " ")
+		p.operand(expr.Right, prec, true)
+	case ast.AssignmentExpr:
+		p.operand(expr.Assignee, assignmentPrecedence, true)
+		p.write(" ", expr.Operator.Value, " ")
+		p.operand(expr.Value, assignmentPrecedence, true)
+	case ast.PrefixExpr:
+		p.write(expr.Operator.Value)
+		if _, nestedPrefix := expr.RightExpr.(ast.PrefixExpr); nestedPrefix || isWord(expr.Operator.Value) {
+			// "- -a" must not become "--a", and "typeof a" needs its space.
+			p.write(" ")
+		}
+
+		p.operand(expr.RightExpr, unaryPrecedence, false)
+	case ast.MemberExpr:
+		p.callee(expr.Member)
+		p.write(".", expr.Property)
+	case ast.ComputedExpr:
+		p.callee(expr.Member)
+		p.write("[")
+		p.nested(func() { p.expr(expr.Property) })
+		p.write("]")
+	case ast.CallExpr:
+		p.callee(expr.Method)
+		p.write("(")
+		p.exprList(expr.Arguments, assignmentPrecedence)
+		p.write(")")
+	case ast.ConditionalExpr:
+		p.operand(expr.Condition, conditionalPrecedence, true)
+		p.write(" ? ")
+		p.nested(func() { p.operand(expr.Consequent, assignmentPrecedence, true) })
+		p.write(" : ")
+		p.operand(expr.Alternate, assignmentPrecedence, true)
+	case ast.RangeExpr:
+		p.operand(expr.Lower, rangePrecedence, true)
+		if expr.Inclusive {
+			p.write("..=")
+		} else {
+			p.write("..")
+		}
+
+		p.operand(expr.Upper, rangePrecedence, true)
+		if expr.Step != nil {
+			p.write(" step ")
+			p.operand(expr.Step, rangePrecedence, true)
+		}
+	case ast.FunctionExpr:
+		p.write("fn")
+		p.function(expr.Parameters, expr.ReturnType, expr.Body, expr.Loc.End.Offset)
+	case ast.NewExpr:
+		p.write("new ")
+		p.expr(expr.Instantiation)
+	case ast.ArrayLiteral:
+		p.write("[")
+		p.exprList(expr.Contents, assignmentPrecedence)
+		p.write("]")
+	case ast.ArrayInstantiationExpr:
+		p.write("[]", typeString(expr.Underlying), "{")
+		p.exprList(expr.Contents, assignmentPrecedence)
+		p.write("}")
+	case ast.StructInstantiationExpr:
+		if p.noStructLiterals {
+			p.nested(func() {
+				p.write("(")
+				p.structLiteral(expr)
+				p.write(")")
+			})
+		} else {
+			p.structLiteral(expr)
+		}
+	default:
+		panic("format: cannot print " + typeName(expr))
+	}
+}
+
+// structLiteral prints the properties in the order they were written.
+func (p *printer) structLiteral(expr ast.StructInstantiationExpr) {
+	names := make([]string, 0, len(expr.Properties))
+	for name := range expr.Properties {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return expr.Properties[names[i]].Span().Start.Offset < expr.Properties[names[j]].Span().Start.Offset
+	})
+
+	p.write(expr.StructName, "{")
+	p.nested(func() {
+		for i, name := range names {
+			if i > 0 {
+				p.write(", ")
+			}
+
+			p.write(name, ": ")
+			p.operand(expr.Properties[name], assignmentPrecedence, true)
+		}
+	})
+
+	p.write("}")
+}
+
+// quote writes s as a string literal, escaping what the lexer decodes.
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '"', '\\':
+			out.WriteRune('\\')
+			out.WriteRune(ch)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if unicode.IsControl(ch) {
+				fmt.Fprintf(&out, `\u{%x}`, ch)
+			} else {
+				out.WriteRune(ch)
+			}
+		}
+	}
+
+	out.WriteByte('"')
+	return out.String()
+}
+
+func isWord(operator string) bool {
+	for _, ch := range operator {
+		if ch < 'a' || ch > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func typeName(node any) string {
+	return fmt.Sprintf("%T", node)
+}
@@ -0,0 +1,37 @@
+package format
+
+import "testing"
+
+func TestParentheses(t *testing.T) {
+	formats(t, []struct{ src, want string }{
+		{"f((x = 1));", "f((x = 1));\n"},
+		{"let a = [(x = 1)];", "let a = [(x = 1)];\n"},
+		{"let a = [(a || b)];", "let a = [a || b];\n"},
+		{"let a = [a ? 1 : 2];", "let a = [a ? 1 : 2];\n"},
+		{"let r = Rect{w: a ? 1 : 2};", "let r = Rect{w: a ? 1 : 2};\n"},
+		{"let a = []int{a ? 1 : 2};", "let a = []int{a ? 1 : 2};\n"},
+		{"let x = (a * b) + ((c));", "let x = a * b + c;\n"},
+		{"let x = (a + b) * c;", "let x = (a + b) * c;\n"},
+		{"let x = a - (b - c);", "let x = a - (b - c);\n"},
+		{"let x = - -a;", "let x = - -a;\n"},
+		{"if (P{x: 1}).x == 1 {}", "if (P{x: 1}).x == 1 {}\n"},
+		{"foreach i in 0..=n-1 step 2 {}", "foreach i in 0..=n - 1 step 2 {}\n"},
+		{"let x = a ? b : c ? d : e;", "let x = a ? b : c ? d : e;\n"},
+		{"let x = (a ? b : c) ? d : e;", "let x = (a ? b : c) ? d : e;\n"},
+		// A function at the start of a statement would read as a declaration
+		{"(fn() { println(1); })();", "(fn() {\n  println(1);\n})();\n"},
+		{"let a = fn(): int { 1; }();", "let a = (fn(): int {\n  1;\n})();\n"},
+		{"(fn() {}).x;", "(fn() {}).x;\n"},
+		{"(fn() {})[0];", "(fn() {})[0];\n"},
+		{"(fn() {}) + 1;", "(fn() {} + 1);\n"},
+		{"(fn() {});", "(fn() {});\n"},
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	formats(t, []struct{ src, want string }{
+		{"let s = `a ${ b+1 } c`;", "let s = `a ${b + 1} c`;\n"},
+		{"let s = `<${`${a}`}> \\${x} \\``;", "let s = `<${`${a}`}> \\${x} \\``;\n"},
+		{"let s = `${P{x: 1}.x}`;", "let s = `${P{x: 1}.x}`;\n"},
+	})
+}
@@ -0,0 +1,140 @@
+// Package format prints syntax trees in the language's canonical style: two
+// space indentation, one statement per line, spaces around binary and
+// assignment operators and only the parentheses that the parser's binding
+// powers require.
+package format
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/lexer"
+	"custom_parser/src/parser"
+	"sort"
+	"strings"
+)
+
+// Source formats the file src. A file with syntax errors is not formatted;
+// its diagnostics are returned instead.
+func Source(name, src string) (string, []diagnostics.Diagnostic) {
+	tokens, lexErrors := lexer.TokenizeMode(name, src, lexer.KeepComments)
+	program, list := parser.Parse(tokens)
+	list = append(diagnostics.FromLexErrors(lexErrors), list...)
+	if diagnostics.HasErrors(list) {
+		return "", list
+	}
+
+	return Program(program), list
+}
+
+// Program prints program together with the comments attached to it. A
+// comment is printed on its own line before the statement or member that
+// follows it, or at the end of the line of the statement it trails.
+func Program(program ast.BlockStmt) string {
+	p := &printer{blockStart: true}
+	p.attach(program.Attached())
+	for _, stmt := range program.Body {
+		p.stmtLine(stmt)
+	}
+
+	p.flushComments(-1)
+	return p.out.String()
+}
+
+type printer struct {
+	out      strings.Builder
+	indent   int
+	comments []ast.Comment // waiting to be printed, in source order
+
+	lastLine   int  // source line of the last thing printed
+	blockStart bool // nothing has been printed in the current block yet
+
+	// noStructLiterals mirrors the parser: inside an if condition or a
+	// foreach iterable a struct literal has to be parenthesised.
+	noStructLiterals bool
+}
+
+func (p *printer) write(parts ...string) {
+	for _, part := range parts {
+		p.out.WriteString(part)
+	}
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat("  ", p.indent))
+}
+
+// attach queues the comments of the node about to be printed. Comments
+// inside the node are printed as the nested nodes after them are reached.
+func (p *printer) attach(comments ast.Comments) {
+	p.comments = append(p.comments, comments.Leading...)
+	p.comments = append(p.comments, comments.Trailing...)
+	sort.SliceStable(p.comments, func(i, j int) bool {
+		return p.comments[i].Loc.Start.Offset < p.comments[j].Loc.Start.Offset
+	})
+}
+
+// startLine begins the line of a node starting at pos, printing the comments
+// before it and keeping one blank line where the source had any.
+func (p *printer) startLine(pos lexer.Position) {
+	p.flushComments(pos.Offset)
+	p.separate(pos.Line)
+	p.writeIndent()
+}
+
+// endLine finishes the line of a node ending at pos, keeping a comment that
+// followed it on the same source line. Comments from inside the node that
+// no nested statement printed, such as one between two arguments, follow
+// on lines of their own.
+func (p *printer) endLine(pos lexer.Position) {
+	n := 0
+	for n < len(p.comments) && p.comments[n].Loc.Start.Offset < pos.Offset {
+		n++
+	}
+
+	inside := p.comments[:n]
+	p.comments = p.comments[n:]
+	p.lastLine = pos.Line
+	if len(p.comments) > 0 {
+		next := p.comments[0]
+		if next.Loc.Start.Line == pos.Line {
+			p.write(" ", next.Text)
+			p.comments = p.comments[1:]
+			p.lastLine = next.Loc.End.Line
+		}
+	}
+
+	p.write("\n")
+	for _, comment := range inside {
+		p.writeIndent()
+		p.write(comment.Text, "\n")
+	}
+}
+
+func (p *printer) separate(line int) {
+	if !p.blockStart && line-p.lastLine > 1 {
+		p.write("\n")
+	}
+
+	p.blockStart = false
+}
+
+// flushComments prints, one per line, every comment before offset; -1
+// prints all that remain.
+func (p *printer) flushComments(offset int) {
+	for len(p.comments) > 0 {
+		next := p.comments[0]
+		if offset >= 0 && next.Loc.Start.Offset >= offset {
+			return
+		}
+
+		p.comments = p.comments[1:]
+		p.startLine(next.Loc.Start)
+		p.write(next.Text, "\n")
+		p.lastLine = next.Loc.End.Line
+	}
+}
+
+// hasCommentsBefore reports whether a comment starts before offset.
+func (p *printer) hasCommentsBefore(offset int) bool {
+	return len(p.comments) > 0 && p.comments[0].Loc.Start.Offset < offset
+}
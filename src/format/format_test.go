@@ -0,0 +1,93 @@
+package format
+
+import (
+	"custom_parser/src/lexer"
+	"custom_parser/src/parser"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/sanity-io/litter"
+)
+
+// dump prints the tree of src without positions or comments, which
+// formatting is allowed to change.
+func dump(t *testing.T, name, src string) string {
+	t.Helper()
+	tokens, _ := lexer.TokenizeFile(name, src)
+	program, list := parser.Parse(tokens)
+	if len(list) > 0 {
+		t.Fatalf("%s does not parse: %v", name, list[0])
+	}
+
+	options := litter.Config
+	options.FieldExclusions = regexp.MustCompile(`^(Loc|ValueLoc|IndexLoc|Span|Comments)$`)
+	return options.Sdump(program)
+}
+
+// roundTrip formats src and checks that the result parses to the same tree
+// and is left alone when formatted again.
+func roundTrip(t *testing.T, name, src string) string {
+	t.Helper()
+	formatted, list := Source(name, src)
+	if len(list) > 0 {
+		t.Fatalf("%s: %v", name, list[0])
+	}
+
+	if before, after := dump(t, name, src), dump(t, name+" (formatted)", formatted); before != after {
+		t.Errorf("%s: formatting changed the tree\nsource:\n%s\nformatted:\n%s", name, src, formatted)
+	}
+
+	again, _ := Source(name, formatted)
+	if again != formatted {
+		t.Errorf("%s: formatting is not idempotent\nfirst:\n%s\nsecond:\n%s", name, formatted, again)
+	}
+
+	return formatted
+}
+
+func TestExamplesRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*.lang")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples found: %v", err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			roundTrip(t, file, string(src))
+		})
+	}
+}
+
+// formats checks that each source formats to the wanted text, and
+// round-trips.
+func formats(t *testing.T, tests []struct{ src, want string }) {
+	t.Helper()
+	for _, test := range tests {
+		if got := roundTrip(t, "<test>", test.src); got != test.want {
+			t.Errorf("%s\ngot\n%s\nwant\n%s", test.src, got, test.want)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	formats(t, []struct{ src, want string }{
+		{"// leading\nlet x = 1; // trailing\n", "// leading\nlet x = 1; // trailing\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"let a = 1;\n// end of file\n", "let a = 1;\n// end of file\n"},
+		{"/* block\n   two */\nlet a = 1;\n", "/* block\n   two */\nlet a = 1;\n"},
+		{"/// Adds.\n/// Twice.\nfn add() {}\n", "/// Adds.\n/// Twice.\nfn add() {}\n"},
+		{"fn f() {\n  let a = 1;\n  // last\n}\n", "fn f() {\n  let a = 1;\n  // last\n}\n"},
+		{"class C {\n  // field\n  let x: int; // x\n\n  fn f() {} // f\n}\n", "class C {\n  // field\n  let x: int; // x\n\n  fn f() {} // f\n}\n"},
+		{"struct P {\n  x: int; // x\n  // g\n  fn g() {}\n}\n", "struct P {\n  x: int; // x\n  // g\n  fn g() {}\n}\n"},
+		// A comment with nowhere to go on its line moves rather than being lost
+		{"if a { // why\n  b();\n} // after\n", "if a {\n  // why\n  b();\n} // after\n"},
+		{"let c = f(1, /* arg */ 2);\n", "let c = f(1, 2);\n/* arg */\n"},
+	})
+}
@@ -0,0 +1,307 @@
+package format
+
+import (
+	"custom_parser/src/ast"
+	"sort"
+)
+
+func (p *printer) stmtLine(stmt ast.Stmt) {
+	span := stmt.Span()
+	p.attach(stmt.Attached())
+	p.startLine(span.Start)
+	p.stmt(stmt)
+	p.endLine(span.End)
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case ast.ExpressionStmt:
+		if startsWithFunction(stmt.Expression) {
+			p.operand(stmt.Expression, primaryPrecedence, true)
+		} else {
+			p.expr(stmt.Expression)
+		}
+
+		p.write(";")
+	case ast.VarDeclStmt:
+		p.varDecl(stmt.IsConstant, stmt.VariableName, stmt.ExplicitType, stmt.AssignedValue)
+	case ast.BlockStmt:
+		p.block(stmt.Body, stmt.Loc.End.Offset)
+	case ast.FunctionDeclStmt:
+		p.write("fn ", stmt.Name)
+		p.function(stmt.Parameters, stmt.ReturnType, stmt.Body, stmt.Loc.End.Offset)
+	case ast.IfStmt:
+		p.ifStmt(stmt)
+	case ast.ImportStmt:
+		p.write("import ", stmt.Name)
+		if stmt.From != stmt.Name {
+			p.write(" from ", quote(stmt.From))
+		}
+
+		p.write(";")
+	case ast.ReturnStmt:
+		p.write("return")
+		if stmt.Value != nil {
+			p.write(" ")
+			p.expr(stmt.Value)
+		}
+
+		p.write(";")
+	case ast.BreakStmt:
+		p.branch("break", stmt.Label)
+	case ast.ContinueStmt:
+		p.branch("continue", stmt.Label)
+	case ast.WhileStmt:
+		p.label(stmt.Label)
+		p.write("while ")
+		p.header(stmt.Condition, assignmentPrecedence)
+		p.write(" ")
+		p.block(stmt.Body, stmt.Loc.End.Offset)
+	case ast.ForStmt:
+		p.forStmt(stmt)
+	case ast.ForeachStmt:
+		p.label(stmt.Label)
+		p.write("foreach ", stmt.Value)
+		if stmt.Index != "" {
+			p.write(", ", stmt.Index)
+		}
+
+		p.write(" in ")
+		p.header(stmt.Iterable, 0)
+		p.write(" ")
+		p.block(stmt.Body, stmt.Loc.End.Offset)
+	case ast.ClassDeclarationStmt:
+		p.classDecl(stmt)
+	case ast.StructDeclStmt:
+		p.structDecl(stmt)
+	default:
+		panic("format: cannot print " + typeName(stmt))
+	}
+}
+
+func (p *printer) varDecl(isConstant bool, name string, explicitType ast.Type, value ast.Expr) {
+	if isConstant {
+		p.write("const ", name)
+	} else {
+		p.write("let ", name)
+	}
+
+	if explicitType != nil {
+		p.write(": ", typeString(explicitType))
+	}
+
+	if value != nil {
+		p.write(" = ")
+		p.operand(value, assignmentPrecedence, true)
+	}
+
+	p.write(";")
+}
+
+// block prints body between braces. end is the offset just past the closing
+// brace; comments before it belong inside the block.
+func (p *printer) block(body []ast.Stmt, end int) {
+	closing := end - 1
+	if len(body) == 0 && !p.hasCommentsBefore(closing) {
+		p.write("{}")
+		return
+	}
+
+	// Statements inside a function expression may use struct literals freely.
+	noStructLiterals := p.noStructLiterals
+	p.noStructLiterals = false
+
+	p.write("{\n")
+	p.indent++
+	p.blockStart = true
+	for _, stmt := range body {
+		p.stmtLine(stmt)
+	}
+
+	p.flushComments(closing)
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+	p.noStructLiterals = noStructLiterals
+}
+
+func (p *printer) function(params []ast.Parameter, returnType ast.Type, body []ast.Stmt, end int) {
+	p.write("(")
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+
+		p.write(param.Name, ": ", typeString(param.Type))
+	}
+
+	p.write(")")
+	if returnType != nil {
+		p.write(": ", typeString(returnType))
+	}
+
+	p.write(" ")
+	p.block(body, end)
+}
+
+func (p *printer) ifStmt(stmt ast.IfStmt) {
+	p.write("if ")
+	p.header(stmt.Condition, assignmentPrecedence)
+	p.write(" ")
+	p.stmt(stmt.Consequent)
+
+	if stmt.Alternate != nil {
+		p.write(" else ")
+		p.stmt(stmt.Alternate)
+	}
+}
+
+func (p *printer) forStmt(stmt ast.ForStmt) {
+	p.label(stmt.Label)
+	p.write("for ")
+	if stmt.Init != nil {
+		p.stmt(stmt.Init)
+	} else {
+		p.write(";")
+	}
+
+	if stmt.Condition != nil {
+		p.write(" ")
+		p.header(stmt.Condition, assignmentPrecedence)
+	}
+
+	p.write(";")
+	if stmt.Post != nil {
+		p.write(" ")
+		p.header(stmt.Post, 0)
+	}
+
+	p.write(" ")
+	p.block(stmt.Body, stmt.Loc.End.Offset)
+}
+
+func (p *printer) label(label string) {
+	if label != "" {
+		p.write(label, ": ")
+	}
+}
+
+// branch prints a break or continue.
+func (p *printer) branch(keyword, label string) {
+	p.write(keyword)
+	if label != "" {
+		p.write(" ", label)
+	}
+
+	p.write(";")
+}
+
+// member is a class or struct member together with how to print it, so the
+// members can be printed in source order.
+type member struct {
+	span  int
+	print func()
+}
+
+func (p *printer) members(members []member, end int) {
+	sort.Slice(members, func(i, j int) bool { return members[i].span < members[j].span })
+	closing := end - 1
+	if len(members) == 0 && !p.hasCommentsBefore(closing) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{\n")
+	p.indent++
+	p.blockStart = true
+	for _, member := range members {
+		member.print()
+	}
+
+	p.flushComments(closing)
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+}
+
+func (p *printer) classDecl(stmt ast.ClassDeclarationStmt) {
+	members := []member{}
+	for _, field := range stmt.Fields {
+		members = append(members, member{field.Loc.Start.Offset, func() {
+			p.attach(field.Comments)
+			p.startLine(field.Loc.Start)
+			if field.IsStatic {
+				p.write("static ")
+			}
+
+			p.varDecl(field.IsConstant, field.Name, field.Type, field.DefaultValue)
+			p.endLine(field.Loc.End)
+		}})
+	}
+
+	methods := stmt.Methods
+	if stmt.Constructor != nil {
+		methods = append(methods, *stmt.Constructor)
+	}
+
+	for _, method := range methods {
+		members = append(members, member{method.Loc.Start.Offset, func() {
+			p.attach(method.Comments)
+			p.startLine(method.Loc.Start)
+			if method.IsStatic {
+				p.write("static ")
+			}
+
+			p.write("fn ", method.Name)
+			p.function(method.Parameters, method.ReturnType, method.Body, method.Loc.End.Offset)
+			p.endLine(method.Loc.End)
+		}})
+	}
+
+	p.write("class ", stmt.Name, " ")
+	p.members(members, stmt.Loc.End.Offset)
+}
+
+func (p *printer) structDecl(stmt ast.StructDeclStmt) {
+	members := []member{}
+	for name, property := range stmt.Properties {
+		members = append(members, member{property.Loc.Start.Offset, func() {
+			p.attach(property.Comments)
+			p.startLine(property.Loc.Start)
+			if property.IsStatic {
+				p.write("static ")
+			}
+
+			p.write(name, ": ", typeString(property.Type), ";")
+			p.endLine(property.Loc.End)
+		}})
+	}
+
+	for name, method := range stmt.Methods {
+		members = append(members, member{method.Loc.Start.Offset, func() {
+			p.attach(method.Comments)
+			p.startLine(method.Loc.Start)
+			if method.IsStatic {
+				p.write("static ")
+			}
+
+			p.write("fn ", name)
+			p.function(method.Parameters, method.ReturnType, method.Body, method.Loc.End.Offset)
+			p.endLine(method.Loc.End)
+		}})
+	}
+
+	p.write("struct ", stmt.StructName, " ")
+	p.members(members, stmt.Loc.End.Offset)
+}
+
+func typeString(t ast.Type) string {
+	switch t := t.(type) {
+	case ast.SymbolType:
+		return t.Name
+	case ast.ArrayType:
+		return "[]" + typeString(t.Underlying)
+	default:
+		panic("format: cannot print " + typeName(t))
+	}
+}
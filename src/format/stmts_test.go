@@ -0,0 +1,10 @@
+package format
+
+import "testing"
+
+func TestLoops(t *testing.T) {
+	formats(t, []struct{ src, want string }{
+		{"outer: for let i = 0; i < 3; i += 1 { break outer; }", "outer: for let i = 0; i < 3; i += 1 {\n  break outer;\n}\n"},
+		{"foreach v, i in xs { }", "foreach v, i in xs {}\n"},
+	})
+}
@@ -0,0 +1,122 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func registerBuiltins(i *Interpreter) {
+	global := func(name string, fn func(args []Value) (Value, error)) {
+		i.globals.Declare(name, NativeFunction{Name: name, Fn: fn}, true)
+	}
+
+	global("println", func(args []Value) (Value, error) {
+		fmt.Fprintln(i.stdout, joinValues(args))
+		return NullValue{}, nil
+	})
+
+	global("print", func(args []Value) (Value, error) {
+		fmt.Fprint(i.stdout, joinValues(args))
+		return NullValue{}, nil
+	})
+
+	global("len", func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument but received %d", len(args))
+		}
+
+		switch arg := args[0].(type) {
+		case *ArrayValue:
+			return IntValue(len(arg.Elements)), nil
+		case StringValue:
+			return IntValue(utf8.RuneCountInString(string(arg))), nil
+		}
+
+		return nil, fmt.Errorf("cannot take the length of %s", typeName(args[0]))
+	})
+
+	i.modules["time"] = &ObjectValue{Name: "time", Fields: map[string]Value{
+		// Times and durations are numbers of milliseconds
+		"second": IntValue(1000),
+		"now": NativeFunction{Name: "time.now", Fn: func(args []Value) (Value, error) {
+			return IntValue(time.Now().UnixMilli()), nil
+		}},
+		"hours": NativeFunction{Name: "time.hours", Fn: func(args []Value) (Value, error) {
+			hours, err := numberArg(args, 0)
+			return IntValue(hours * float64(time.Hour/time.Millisecond)), err
+		}},
+	}}
+
+	i.modules["path"] = &ObjectValue{Name: "path", Fields: map[string]Value{
+		"join": NativeFunction{Name: "path.join", Fn: func(args []Value) (Value, error) {
+			parts := make([]string, len(args))
+			for n, arg := range args {
+				parts[n] = arg.String()
+			}
+
+			return StringValue(filepath.Join(parts...)), nil
+		}},
+	}}
+
+	i.modules["fs"] = &ObjectValue{Name: "fs", Fields: map[string]Value{
+		"readDir": NativeFunction{Name: "fs.readDir", Fn: func(args []Value) (Value, error) {
+			entries, err := os.ReadDir(stringArg(args, 0))
+			if err != nil {
+				return nil, err
+			}
+
+			names := make([]Value, len(entries))
+			for n, entry := range entries {
+				names[n] = StringValue(entry.Name())
+			}
+
+			return &ArrayValue{Elements: names}, nil
+		}},
+		"stat": NativeFunction{Name: "fs.stat", Fn: func(args []Value) (Value, error) {
+			info, err := os.Stat(stringArg(args, 0))
+			if err != nil {
+				return nil, err
+			}
+
+			return &ObjectValue{Name: "FileInfo", Fields: map[string]Value{
+				"name":         StringValue(info.Name()),
+				"size":         IntValue(info.Size()),
+				"creationTime": IntValue(info.ModTime().UnixMilli()),
+			}}, nil
+		}},
+	}}
+}
+
+func joinValues(args []Value) string {
+	parts := make([]string, len(args))
+	for n, arg := range args {
+		parts[n] = arg.String()
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func numberArg(args []Value, n int) (float64, error) {
+	if n >= len(args) {
+		return 0, fmt.Errorf("missing argument %d", n+1)
+	}
+
+	number, ok := toFloat(args[n])
+	if !ok {
+		return 0, fmt.Errorf("argument %d must be a number, received %s", n+1, typeName(args[n]))
+	}
+
+	return number, nil
+}
+
+func stringArg(args []Value, n int) string {
+	if n >= len(args) {
+		return ""
+	}
+
+	return args[n].String()
+}
@@ -0,0 +1,74 @@
+package interpreter
+
+import (
+	"custom_parser/src/ast"
+	"fmt"
+)
+
+type binding struct {
+	value    Value
+	constant bool
+	declared ast.Type // nil when not declared
+}
+
+// Environment is a single lexical scope. Lookups and assignments walk up
+// through parent scopes.
+type Environment struct {
+	parent   *Environment
+	bindings map[string]*binding
+}
+
+func NewEnvironment(parent *Environment) *Environment {
+	return &Environment{
+		parent:   parent,
+		bindings: map[string]*binding{},
+	}
+}
+
+func (env *Environment) Declare(name string, value Value, constant bool) error {
+	return env.DeclareTyped(name, value, constant, nil)
+}
+
+// DeclareTyped declares a variable of type declared. Values later assigned
+// to it are converted to that type, as the value given here should be.
+func (env *Environment) DeclareTyped(name string, value Value, constant bool, declared ast.Type) error {
+	if _, exists := env.bindings[name]; exists {
+		return fmt.Errorf("%s has already been declared in this scope", name)
+	}
+
+	env.bindings[name] = &binding{value: value, constant: constant, declared: declared}
+	return nil
+}
+
+func (env *Environment) Assign(name string, value Value) error {
+	b := env.resolve(name)
+	if b == nil {
+		return fmt.Errorf("cannot assign to undeclared variable %s", name)
+	}
+
+	if b.constant {
+		return fmt.Errorf("cannot assign to constant %s", name)
+	}
+
+	b.value = convert(value, b.declared)
+	return nil
+}
+
+func (env *Environment) Lookup(name string) (Value, bool) {
+	b := env.resolve(name)
+	if b == nil {
+		return nil, false
+	}
+
+	return b.value, true
+}
+
+func (env *Environment) resolve(name string) *binding {
+	for scope := env; scope != nil; scope = scope.parent {
+		if b, exists := scope.bindings[name]; exists {
+			return b
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,610 @@
+package interpreter
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/lexer"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+func evalExpr(i *Interpreter, expr ast.Expr, env *Environment) Value {
+	switch expr := expr.(type) {
+	case ast.NumberExpr:
+		if expr.Integral {
+			return IntValue(expr.Int)
+		}
+
+		return FloatValue(expr.Value)
+	case ast.StringExpr:
+		return StringValue(expr.Value)
+	case ast.BooleanExpr:
+		return BooleanValue(expr.Value)
+	case ast.NullExpr:
+		return NullValue{}
+	case ast.TemplateExpr:
+		var out strings.Builder
+		for _, part := range expr.Parts {
+			out.WriteString(evalExpr(i, part, env).String())
+		}
+
+		return StringValue(out.String())
+	case ast.SymbolExpr:
+		value, exists := env.Lookup(expr.Value)
+		if !exists {
+			fail(expr.Loc, "undefined variable %s", expr.Value)
+		}
+
+		return value
+	case ast.PrefixExpr:
+		return evalPrefixExpr(i, expr, env)
+	case ast.BinaryExpr:
+		return evalBinaryExpr(i, expr, env)
+	case ast.ConditionalExpr:
+		if isTruthy(evalExpr(i, expr.Condition, env)) {
+			return evalExpr(i, expr.Consequent, env)
+		}
+
+		return evalExpr(i, expr.Alternate, env)
+	case ast.AssignmentExpr:
+		return evalAssignmentExpr(i, expr, env)
+	case ast.MemberExpr:
+		return getMember(evalExpr(i, expr.Member, env), expr.Property, expr.Loc)
+	case ast.ComputedExpr:
+		return getIndex(evalExpr(i, expr.Member, env), evalExpr(i, expr.Property, env), expr.Loc)
+	case ast.CallExpr:
+		callee := evalExpr(i, expr.Method, env)
+		args := make([]Value, len(expr.Arguments))
+		for n, argument := range expr.Arguments {
+			args[n] = evalExpr(i, argument, env)
+		}
+
+		return callFunction(i, callee, args, expr.Loc)
+	case ast.FunctionExpr:
+		return &FunctionValue{
+			Parameters: expr.Parameters,
+			ReturnType: expr.ReturnType,
+			Body:       expr.Body,
+			Closure:    env,
+		}
+	case ast.ArrayLiteral:
+		return &ArrayValue{Elements: evalExprs(i, expr.Contents, env)}
+	case ast.ArrayInstantiationExpr:
+		return &ArrayValue{Elements: evalExprs(i, expr.Contents, env)}
+	case ast.NewExpr:
+		return evalNewExpr(i, expr, env)
+	case ast.StructInstantiationExpr:
+		return evalStructInstantiationExpr(i, expr, env)
+	case ast.RangeExpr:
+		return evalRange(i, expr, env).array(expr.Loc)
+	default:
+		fail(expr.Span(), "%T is not supported by the interpreter yet", expr)
+		return nil
+	}
+}
+
+func evalExprs(i *Interpreter, exprs []ast.Expr, env *Environment) []Value {
+	values := make([]Value, len(exprs))
+	for n, expr := range exprs {
+		values[n] = evalExpr(i, expr, env)
+	}
+
+	return values
+}
+
+func evalPrefixExpr(i *Interpreter, expr ast.PrefixExpr, env *Environment) Value {
+	right := evalExpr(i, expr.RightExpr, env)
+
+	switch expr.Operator.Kind {
+	case lexer.DASH:
+		switch number := right.(type) {
+		case IntValue:
+			return -number
+		case FloatValue:
+			return -number
+		}
+
+		fail(expr.Loc, "cannot negate %s", typeName(right))
+	case lexer.TILDE:
+		number, ok := right.(IntValue)
+		if !ok {
+			fail(expr.Loc, "operator ~ is not defined for %s", typeName(right))
+		}
+
+		return ^number
+	case lexer.NOT:
+		return BooleanValue(!isTruthy(right))
+	case lexer.TYPEOF:
+		return StringValue(typeName(right))
+	}
+
+	fail(expr.Loc, "unsupported prefix operator %s", expr.Operator.Value)
+	return nil
+}
+
+func evalBinaryExpr(i *Interpreter, expr ast.BinaryExpr, env *Environment) Value {
+	left := evalExpr(i, expr.Left, env)
+
+	// Logical operators short-circuit
+	switch expr.Operator.Kind {
+	case lexer.AND:
+		if !isTruthy(left) {
+			return BooleanValue(false)
+		}
+
+		return BooleanValue(isTruthy(evalExpr(i, expr.Right, env)))
+	case lexer.OR:
+		if isTruthy(left) {
+			return BooleanValue(true)
+		}
+
+		return BooleanValue(isTruthy(evalExpr(i, expr.Right, env)))
+	}
+
+	right := evalExpr(i, expr.Right, env)
+	return binaryOperation(expr.Operator, left, right, expr.Loc)
+}
+
+func binaryOperation(operator lexer.Token, left, right Value, span lexer.Span) Value {
+	switch operator.Kind {
+	case lexer.EQUALS:
+		return BooleanValue(valuesEqual(left, right))
+	case lexer.NOT_EQUALS:
+		return BooleanValue(!valuesEqual(left, right))
+	}
+
+	if operator.Kind == lexer.PLUS {
+		_, leftIsString := left.(StringValue)
+		_, rightIsString := right.(StringValue)
+		if leftIsString || rightIsString {
+			return StringValue(left.String() + right.String())
+		}
+	}
+
+	if l, ok := left.(StringValue); ok {
+		if r, ok := right.(StringValue); ok {
+			switch operator.Kind {
+			case lexer.LESS:
+				return BooleanValue(l < r)
+			case lexer.LESS_EQUALS:
+				return BooleanValue(l <= r)
+			case lexer.GREATER:
+				return BooleanValue(l > r)
+			case lexer.GREATER_EQUALS:
+				return BooleanValue(l >= r)
+			}
+		}
+	}
+
+	if l, ok := left.(IntValue); ok {
+		if r, ok := right.(IntValue); ok {
+			return intOperation(operator, l, r, span)
+		}
+	}
+
+	l, leftOk := toFloat(left)
+	r, rightOk := toFloat(right)
+	if !leftOk || !rightOk {
+		fail(span, "operator %s is not defined for %s and %s", operator.Value, typeName(left), typeName(right))
+	}
+
+	return floatOperation(operator, l, r, span)
+}
+
+// intOperation wraps around on overflow, like 64-bit machine arithmetic.
+// Division truncates toward zero.
+func intOperation(operator lexer.Token, l, r IntValue, span lexer.Span) Value {
+	switch operator.Kind {
+	case lexer.PLUS:
+		return l + r
+	case lexer.DASH:
+		return l - r
+	case lexer.STAR:
+		return l * r
+	case lexer.SLASH, lexer.PERCENT:
+		if r == 0 {
+			fail(span, "division by zero")
+		}
+
+		if operator.Kind == lexer.SLASH {
+			return l / r
+		}
+
+		return l % r
+	case lexer.AMPERSAND:
+		return l & r
+	case lexer.PIPE:
+		return l | r
+	case lexer.CARET:
+		return l ^ r
+	case lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT:
+		if r < 0 {
+			fail(span, "negative shift count %d", r)
+		}
+
+		if operator.Kind == lexer.SHIFT_LEFT {
+			return l << r
+		}
+
+		return l >> r
+	case lexer.LESS:
+		return BooleanValue(l < r)
+	case lexer.LESS_EQUALS:
+		return BooleanValue(l <= r)
+	case lexer.GREATER:
+		return BooleanValue(l > r)
+	case lexer.GREATER_EQUALS:
+		return BooleanValue(l >= r)
+	}
+
+	fail(span, "unsupported binary operator %s", operator.Value)
+	return nil
+}
+
+func floatOperation(operator lexer.Token, l, r float64, span lexer.Span) Value {
+	switch operator.Kind {
+	case lexer.PLUS:
+		return FloatValue(l + r)
+	case lexer.DASH:
+		return FloatValue(l - r)
+	case lexer.STAR:
+		return FloatValue(l * r)
+	case lexer.SLASH:
+		if r == 0 {
+			fail(span, "division by zero")
+		}
+		return FloatValue(l / r)
+	case lexer.PERCENT:
+		if r == 0 {
+			fail(span, "division by zero")
+		}
+		return FloatValue(math.Mod(l, r))
+	case lexer.LESS:
+		return BooleanValue(l < r)
+	case lexer.LESS_EQUALS:
+		return BooleanValue(l <= r)
+	case lexer.GREATER:
+		return BooleanValue(l > r)
+	case lexer.GREATER_EQUALS:
+		return BooleanValue(l >= r)
+	}
+
+	fail(span, "operator %s is not defined for floats", operator.Value)
+	return nil
+}
+
+func evalAssignmentExpr(i *Interpreter, expr ast.AssignmentExpr, env *Environment) Value {
+	value := evalExpr(i, expr.Value, env)
+
+	// Compound assignments read the current value first
+	if expr.Operator.Kind != lexer.ASSIGNMENT {
+		operator := lexer.Token{Kind: lexer.PLUS, Value: "+"}
+		if expr.Operator.Kind == lexer.MINUS_EQUALS {
+			operator = lexer.Token{Kind: lexer.DASH, Value: "-"}
+		}
+
+		value = binaryOperation(operator, evalExpr(i, expr.Assignee, env), value, expr.Loc)
+	}
+
+	switch assignee := expr.Assignee.(type) {
+	case ast.SymbolExpr:
+		if err := env.Assign(assignee.Value, value); err != nil {
+			fail(expr.Loc, "%s", err)
+		}
+	case ast.MemberExpr:
+		setMember(evalExpr(i, assignee.Member, env), assignee.Property, value, expr.Loc)
+	case ast.ComputedExpr:
+		setIndex(evalExpr(i, assignee.Member, env), evalExpr(i, assignee.Property, env), value, expr.Loc)
+	default:
+		fail(expr.Assignee.Span(), "invalid assignment target")
+	}
+
+	return value
+}
+
+// intRange is the sequence of ints a range expression stands for. A range
+// evaluates to an array of them, except as the iterable of a foreach, which
+// walks the range without building the array.
+type intRange struct {
+	lower, step int64
+	empty       bool
+	last        uint64 // index of the final int, so a full int64 span fits
+}
+
+// maxRangeArray is the most ints a range is turned into an array of.
+const maxRangeArray = 1 << 26
+
+// at is the nth int of the range. The arithmetic wraps like the int64 it
+// stands for, so it stays exact for ranges spanning more than MaxInt64.
+func (r intRange) at(n uint64) IntValue {
+	return IntValue(uint64(r.lower) + n*uint64(r.step))
+}
+
+func (r intRange) array(span lexer.Span) *ArrayValue {
+	if r.empty {
+		return &ArrayValue{Elements: []Value{}}
+	}
+
+	if r.last >= maxRangeArray {
+		fail(span, "range has more than %d ints, too many to turn into an array; iterate over it with foreach instead", maxRangeArray)
+	}
+
+	elements := make([]Value, r.last+1)
+	for n := range elements {
+		elements[n] = r.at(uint64(n))
+	}
+
+	return &ArrayValue{Elements: elements}
+}
+
+func evalRange(i *Interpreter, expr ast.RangeExpr, env *Environment) intRange {
+	lower := rangeInt(evalExpr(i, expr.Lower, env), "bound", expr.Lower.Span())
+	upper := rangeInt(evalExpr(i, expr.Upper, env), "bound", expr.Upper.Span())
+	step := int64(1)
+	if expr.Step != nil {
+		step = rangeInt(evalExpr(i, expr.Step, env), "step", expr.Step.Span())
+	}
+
+	if step == 0 {
+		fail(expr.Step.Span(), "range step cannot be zero")
+	}
+
+	empty := upper == lower && !expr.Inclusive
+	if empty || step > 0 && upper < lower || step < 0 && upper > lower {
+		return intRange{lower: lower, step: step, empty: true}
+	}
+
+	// Counted as unsigned, the distance between two int64s cannot overflow
+	last := upper
+	if !expr.Inclusive && step > 0 {
+		last--
+	} else if !expr.Inclusive {
+		last++
+	}
+
+	distance, size := uint64(last)-uint64(lower), uint64(step)
+	if step < 0 {
+		distance, size = uint64(lower)-uint64(last), -uint64(step)
+	}
+
+	return intRange{lower: lower, step: step, last: distance / size}
+}
+
+func rangeInt(value Value, what string, span lexer.Span) int64 {
+	n, ok := value.(IntValue)
+	if !ok {
+		fail(span, "range %s must be an int, received %s", what, typeName(value))
+	}
+
+	return int64(n)
+}
+
+func getMember(object Value, property string, span lexer.Span) Value {
+	switch object := object.(type) {
+	case *InstanceValue:
+		if value, exists := object.Fields[property]; exists {
+			return value
+		}
+
+		if method, exists := object.Class.Methods[property]; exists {
+			return bindThis(method, object)
+		}
+
+		fail(span, "%s has no member %s", object.Class.Name, property)
+	case *ClassValue:
+		value, exists := object.Static[property]
+		if !exists {
+			fail(span, "%s has no static member %s", object.Name, property)
+		}
+
+		return value
+	case *ObjectValue:
+		value, exists := object.Fields[property]
+		if !exists {
+			fail(span, "%s has no member %s", object, property)
+		}
+
+		return value
+	case *ArrayValue:
+		switch property {
+		case "length":
+			return IntValue(len(object.Elements))
+		case "push":
+			return NativeFunction{Name: "push", Fn: func(args []Value) (Value, error) {
+				object.Elements = append(object.Elements, args...)
+				return IntValue(len(object.Elements)), nil
+			}}
+		}
+	case StringValue:
+		// Strings are measured in characters, as foreach walks them
+		if property == "length" {
+			return IntValue(utf8.RuneCountInString(string(object)))
+		}
+	}
+
+	fail(span, "%s has no member %s", typeName(object), property)
+	return nil
+}
+
+func setMember(object Value, property string, value Value, span lexer.Span) {
+	switch object := object.(type) {
+	case *ObjectValue:
+		object.Fields[property] = value
+		return
+	case *InstanceValue:
+		object.Fields[property] = convert(value, object.Class.FieldTypes[property])
+		return
+	case *ClassValue:
+		object.Static[property] = convert(value, object.FieldTypes[property])
+		return
+	}
+
+	fail(span, "cannot set member %s on %s", property, typeName(object))
+}
+
+func toIndex(index Value, length int, span lexer.Span) int {
+	number, ok := index.(IntValue)
+	if !ok {
+		fail(span, "index must be an int, received %s", typeName(index))
+	}
+
+	if number < 0 || int(number) >= length {
+		fail(span, "index %s out of range for length %d", number, length)
+	}
+
+	return int(number)
+}
+
+func getIndex(object, index Value, span lexer.Span) Value {
+	switch object := object.(type) {
+	case *ArrayValue:
+		return object.Elements[toIndex(index, len(object.Elements), span)]
+	case StringValue:
+		// Indexed by character rather than byte, so the result is never
+		// half of a multi-byte character
+		chars := []rune(string(object))
+		return StringValue(chars[toIndex(index, len(chars), span)])
+	case *ObjectValue:
+		if key, ok := index.(StringValue); ok {
+			return getMember(object, string(key), span)
+		}
+	}
+
+	fail(span, "cannot index %s with %s", typeName(object), typeName(index))
+	return nil
+}
+
+func setIndex(object, index, value Value, span lexer.Span) {
+	switch object := object.(type) {
+	case *ArrayValue:
+		object.Elements[toIndex(index, len(object.Elements), span)] = value
+		return
+	case *ObjectValue:
+		if key, ok := index.(StringValue); ok {
+			object.Fields[string(key)] = value
+			return
+		}
+	}
+
+	fail(span, "cannot index %s with %s", typeName(object), typeName(index))
+}
+
+// maxCallDepth bounds the calls in progress at once, so that unbounded
+// recursion stops with a runtime error rather than overflowing the stack.
+const maxCallDepth = 10000
+
+func callFunction(i *Interpreter, callee Value, args []Value, span lexer.Span) Value {
+	switch fn := callee.(type) {
+	case NativeFunction:
+		result, err := fn.Fn(args)
+		if err != nil {
+			fail(span, "%s: %s", fn.Name, err)
+		}
+
+		return result
+	case *FunctionValue:
+		if len(args) != len(fn.Parameters) {
+			fail(span, "%s expects %d arguments but received %d", fn, len(fn.Parameters), len(args))
+		}
+
+		if i.depth == maxCallDepth {
+			fail(span, "maximum call depth exceeded")
+		}
+
+		i.depth++
+		defer func() { i.depth-- }()
+
+		scope := NewEnvironment(fn.Closure)
+		for n, param := range fn.Parameters {
+			if err := scope.DeclareTyped(param.Name, convert(args[n], param.Type), false, param.Type); err != nil {
+				fail(param.Loc, "%s", err)
+			}
+		}
+
+		return convert(callResult(execBody(i, fn.Body, scope), fn.Body), fn.ReturnType)
+	}
+
+	fail(span, "%s is not callable", typeName(callee))
+	return nil
+}
+
+// callResult is the value of a call whose body finished with outcome: the
+// value returned or, failing that, the value of a final expression
+// statement. A function ending in any other statement returns null.
+func callResult(outcome outcome, body []ast.Stmt) Value {
+	if outcome.flow == returning {
+		return outcome.value
+	}
+
+	if len(body) > 0 {
+		if _, ok := body[len(body)-1].(ast.ExpressionStmt); ok {
+			return outcome.value
+		}
+	}
+
+	return NullValue{}
+}
+
+func evalNewExpr(i *Interpreter, expr ast.NewExpr, env *Environment) Value {
+	callee := evalExpr(i, expr.Instantiation.Method, env)
+	class, ok := callee.(*ClassValue)
+	if !ok {
+		fail(expr.Instantiation.Method.Span(), "cannot instantiate %s", typeName(callee))
+	}
+
+	args := evalExprs(i, expr.Instantiation.Arguments, env)
+	instance := &InstanceValue{Class: class, Fields: map[string]Value{}}
+	for _, field := range class.Fields {
+		var value Value = NullValue{}
+		if field.DefaultValue != nil {
+			value = convert(evalExpr(i, field.DefaultValue, class.Closure), field.Type)
+		}
+
+		instance.Fields[field.Name] = value
+	}
+
+	if constructor := class.Constructor; constructor != nil {
+		callFunction(i, bindThis(constructor, instance), args, expr.Loc)
+	} else if len(args) > 0 {
+		fail(expr.Loc, "%s has no constructor but received %d arguments", class.Name, len(args))
+	}
+
+	return instance
+}
+
+// bindThis returns a copy of method whose scope has this bound to instance.
+func bindThis(method *FunctionValue, instance *InstanceValue) *FunctionValue {
+	scope := NewEnvironment(method.Closure)
+	scope.Declare("this", instance, true)
+
+	bound := *method
+	bound.Closure = scope
+	return &bound
+}
+
+func evalStructInstantiationExpr(i *Interpreter, expr ast.StructInstantiationExpr, env *Environment) Value {
+	value, exists := env.Lookup(expr.StructName)
+	structValue, isStruct := value.(*ClassValue)
+	if !exists || !isStruct || !structValue.IsStruct {
+		fail(expr.Loc, "%s is not a struct", expr.StructName)
+	}
+
+	// Fields left out of the literal start out null
+	instance := &InstanceValue{Class: structValue, Fields: map[string]Value{}}
+	for _, field := range structValue.Fields {
+		instance.Fields[field.Name] = NullValue{}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(expr.Properties)) {
+		value := expr.Properties[name]
+		if _, declared := instance.Fields[name]; !declared {
+			fail(value.Span(), "%s has no field %s", structValue.Name, name)
+		}
+
+		instance.Fields[name] = convert(evalExpr(i, value, env), structValue.FieldTypes[name])
+	}
+
+	return instance
+}
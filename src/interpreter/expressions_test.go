@@ -0,0 +1,172 @@
+package interpreter
+
+import "testing"
+
+func TestRanges(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"println(0..5);", "[0, 1, 2, 3, 4]\n"},
+		{"println(0..=5);", "[0, 1, 2, 3, 4, 5]\n"},
+		{"println(0..10 step 3);", "[0, 3, 6, 9]\n"},
+		{"println(5..=0 step -2, 10..0 step -3);", "[5, 3, 1] [10, 7, 4, 1]\n"},
+		{"println(3..3, 5..1, 1..5 step -1);", "[] [] []\n"},
+		{"println(3..=3);", "[3]\n"},
+		{"let xs: []int; xs = 1..=3; xs.push(4); println(xs, len(xs));", "[1, 2, 3, 4] 4\n"},
+		{"println(0..5 step 0);", "range step cannot be zero"},
+		{"println(0..1.5);", "range bound must be an int, received float"},
+		{"let x = 0..9223372036854775807;", "range has more than 67108864 ints, too many to turn into an array; iterate over it with foreach instead"},
+		// foreach walks a range without building the array
+		{"let c = 0; foreach n, i in 0..=9223372036854775807 { c += 1; if i == 2 { break; } } println(c);", "3\n"},
+		{
+			"foreach n in -9223372036854775807-1..=9223372036854775807 step 4611686018427387904 { println(n); }",
+			"-9223372036854775808\n-4611686018427387904\n0\n4611686018427387904\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	class := "class C { let n: int; fn mount(n: int) { this.n = n; } } "
+	tests := []struct{ src, want string }{
+		{class + "let c = new C(4); println(c.n);", "4\n"},
+		{class + "new C();", "<fn C.mount> expects 1 arguments but received 0"},
+		{`class D { fn mount() { println("mounted"); } } new D();`, "mounted\n"},
+		{"class E {} new E(1);", "E has no constructor but received 1 arguments"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`println("héllo".length, len("héllo"), "".length);`, "5 5 0\n"},
+		{`let s = "añb"; println(s[0], s[1], s[2]);`, "a ñ b\n"},
+		{`let s = "ñ"; println(s[1]);`, "index 1 out of range for length 1"},
+		{`foreach ch, i in "añ" { println(i, ch); }`, "0 a\n1 ñ\n"},
+		{`let s = "日本"; for let i = 0; i < s.length; i += 1 { print(s[i]); } println();`, "日本\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"println(7 + 2, 7 - 2, 7 * 2, 7 / 2, 7 % 2);", "9 5 14 3 1\n"},
+		{"println(-7 / 2, -7 % 2);", "-3 -1\n"},
+		{"println(7.0 / 2, 1.5 + 1.5, 7.5 % 2);", "3.5 3 1.5\n"},
+		{"println(1 + 0.5, 2 * 1.5, typeof (1 + 1.0));", "1.5 3 float\n"},
+		{"println(typeof (1 + 1), typeof (4 / 2));", "int int\n"},
+		{"println(9223372036854775807 + 1);", "-9223372036854775808\n"},
+		{"println(9007199254740993 - 9007199254740992);", "1\n"},
+		{"println(6 & 3, 6 | 3, 6 ^ 3, 1 << 4, 16 >> 2, ~0);", "2 7 5 16 4 -1\n"},
+		{`println(1 < 2, 2 <= 2, 1.5 > 1, "a" < "b");`, "true true true true\n"},
+		{"let x = 1; x += 5; x -= 2; println(x);", "4\n"},
+		{"println(1 / 0);", "division by zero"},
+		{"println(1.0 / 0);", "division by zero"},
+		{"println(1 << -1);", "negative shift count -1"},
+		{"println(true - 1);", "operator - is not defined for boolean and int"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestFloatSlots(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"let x: float = 1; println(x / 2, typeof x);", "0.5 float\n"},
+		{"let x: float = 1.5; x = 3; println(x / 2); x += 1; println(typeof x);", "1.5\nfloat\n"},
+		{"fn half(v: float): float { return v / 2; } println(half(3));", "1.5\n"},
+		{"fn f(): float { return 1; } let g = fn(): float { 2; }; println(typeof f(), typeof g());", "float float\n"},
+		{"class C { let x: float = 1; static let y: float = 2; } let c = new C(); c.x = 3; println(typeof c.x, typeof C.y);", "float float\n"},
+		{"struct P { x: float; } let p = P{x: 1}; println(p.x / 2); p.x = 5; println(typeof p.x);", "0.5\nfloat\n"},
+		// Only float slots convert
+		{"let x = 1; let y: number = 1; println(typeof x, typeof y);", "int int\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"fn f(n: int): int { return f(n + 1); } f(0);", "maximum call depth exceeded"},
+		{"fn even(n: int): boolean { return n == 0 || odd(n - 1); } fn odd(n: int): boolean { return n != 0 && even(n - 1); } even(-1);", "maximum call depth exceeded"},
+		{"fn sum(n: int): int { if n == 0 { return 0; } return n + sum(n - 1); } println(sum(5000));", "12502500\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestStructLiterals(t *testing.T) {
+	rect := "struct R { w: int; h: int; static unit: int; } "
+	tests := []struct{ src, want string }{
+		{rect + "let r = R{w: 2, h: 3}; println(r.w * r.h);", "6\n"},
+		{rect + "println(R{w: 2}.h);", "null\n"},
+		{rect + "let r = R{}; r.h = 4; println(r.w, r.h);", "null 4\n"},
+		{rect + "R{w: 1, d: 2};", "R has no field d"},
+		{rect + "R{unit: 1};", "R has no field unit"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"let name = \"w\"; println(`hi ${name}!`);", "hi w!\n"},
+		{"println(`${1 + 1} ${1.5} ${[1, 2]} ${null} ${true}`);", "2 1.5 [1, 2] null true\n"},
+		{"struct P { x: int; } println(`x=${P{x: 3}.x}`);", "x=3\n"},
+		{"let n = 2; println(`<${`${n * 2}`}>`);", "<4>\n"},
+		{"println(`\\${not} \\``);", "${not} `\n"},
+		{"println(typeof `a`);", "string\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`println(true ? "yes" : "no", false ? "yes" : "no");`, "yes no\n"},
+		{"fn abs(n: int): int { n < 0 ? -n : n; } println(abs(-3), abs(4));", "3 4\n"},
+		// Right associative, so a chain reads as else-if
+		{`fn size(n: int): string { n < 10 ? "small" : n < 100 ? "medium" : "large"; } println(size(5), size(50), size(500));`, "small medium large\n"},
+		// Only the chosen branch runs
+		{`fn loud(s: string): string { println(s); s; } println(true ? loud("a") : loud("b"));`, "a\na\n"},
+		{"let x = null ? 1 : 2; println(x);", "2\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
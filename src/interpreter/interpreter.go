@@ -0,0 +1,394 @@
+package interpreter
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/lexer"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+type RuntimeError struct {
+	Message string
+	Span    lexer.Span
+}
+
+func (err RuntimeError) Error() string {
+	return fmt.Sprintf("%s: runtime error: %s", err.Span.Start, err.Message)
+}
+
+type Interpreter struct {
+	globals *Environment
+	modules map[string]Value
+	stdout  io.Writer
+	depth   int // calls of user defined functions in progress
+}
+
+// New creates an interpreter whose builtins write to stdout.
+func New(stdout io.Writer) *Interpreter {
+	i := &Interpreter{
+		globals: NewEnvironment(nil),
+		modules: map[string]Value{},
+		stdout:  stdout,
+	}
+
+	registerBuiltins(i)
+	return i
+}
+
+// Run executes program in the interpreter's global scope. Globals persist
+// between calls, so a program may be run in several pieces.
+func (i *Interpreter) Run(program ast.BlockStmt) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(RuntimeError)
+			if !ok {
+				panic(r)
+			}
+
+			err = runtimeErr
+		}
+	}()
+
+	execBody(i, program.Body, i.globals)
+	return nil
+}
+
+// fail aborts execution with a RuntimeError; Run turns it back into an error.
+func fail(span lexer.Span, format string, args ...any) {
+	panic(RuntimeError{
+		Message: fmt.Sprintf(format, args...),
+		Span:    span,
+	})
+}
+
+// flow is how execution carries on after a statement.
+type flow int
+
+const (
+	proceeding flow = iota // on to the next statement
+	returning              // out of the enclosing function
+	breaking               // out of a loop
+	continuing             // on to the next iteration of a loop
+)
+
+// outcome is how a statement finished.
+type outcome struct {
+	flow  flow
+	label string // the loop named by a break or continue, or ""
+	value Value  // the returned value, or the value of an expression statement
+}
+
+var normal = outcome{value: NullValue{}}
+
+// execStmt runs a single statement. Statements containing a return, break
+// or continue pass its outcome on so that the statements around them stop
+// too.
+func execStmt(i *Interpreter, stmt ast.Stmt, env *Environment) outcome {
+	switch stmt := stmt.(type) {
+	case ast.ExpressionStmt:
+		return outcome{value: evalExpr(i, stmt.Expression, env)}
+	case ast.ReturnStmt:
+		var value Value = NullValue{}
+		if stmt.Value != nil {
+			value = evalExpr(i, stmt.Value, env)
+		}
+
+		return outcome{flow: returning, value: value}
+	case ast.BreakStmt:
+		return outcome{flow: breaking, label: stmt.Label, value: NullValue{}}
+	case ast.ContinueStmt:
+		return outcome{flow: continuing, label: stmt.Label, value: NullValue{}}
+	case ast.BlockStmt:
+		return execBody(i, stmt.Body, NewEnvironment(env))
+	case ast.VarDeclStmt:
+		execVarDeclStmt(i, stmt, env)
+	case ast.FunctionDeclStmt, ast.StructDeclStmt:
+		// Declared by hoist before the body started
+	case ast.IfStmt:
+		if isTruthy(evalExpr(i, stmt.Condition, env)) {
+			return execStmt(i, stmt.Consequent, env)
+		} else if stmt.Alternate != nil {
+			return execStmt(i, stmt.Alternate, env)
+		}
+	case ast.WhileStmt:
+		for isTruthy(evalExpr(i, stmt.Condition, env)) {
+			if result, done := endsLoop(execBody(i, stmt.Body, NewEnvironment(env)), stmt.Label); done {
+				return result
+			}
+		}
+	case ast.ForStmt:
+		return execForStmt(i, stmt, env)
+	case ast.ForeachStmt:
+		return execForeachStmt(i, stmt, env)
+	case ast.ImportStmt:
+		module, exists := i.modules[stmt.From]
+		if !exists {
+			fail(stmt.Loc, "unknown module %s", stmt.From)
+		}
+
+		declare(env, stmt.Name, module, true, stmt.Loc)
+	case ast.ClassDeclarationStmt:
+		execClassDeclStmt(i, stmt, env)
+	case ast.BadStmt:
+		fail(stmt.Loc, "cannot execute a statement containing syntax errors")
+	default:
+		fail(stmt.Span(), "%T is not supported by the interpreter yet", stmt)
+	}
+
+	return normal
+}
+
+// execBody runs body until a statement returns, breaks or continues.
+// Otherwise the outcome is that of the final statement.
+func execBody(i *Interpreter, body []ast.Stmt, env *Environment) outcome {
+	hoist(i, body, env)
+
+	result := normal
+	for _, stmt := range body {
+		result = execStmt(i, stmt, env)
+		if result.flow != proceeding {
+			break
+		}
+	}
+
+	return result
+}
+
+// hoist declares the functions, classes and structs of body before any of
+// it runs, as the checker does, so they may be used above their
+// declarations.
+func hoist(i *Interpreter, body []ast.Stmt, env *Environment) {
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case ast.FunctionDeclStmt:
+			declare(env, stmt.Name, &FunctionValue{
+				Name:       stmt.Name,
+				Parameters: stmt.Parameters,
+				ReturnType: stmt.ReturnType,
+				Body:       stmt.Body,
+				Closure:    env,
+			}, true, stmt.Loc)
+		case ast.ClassDeclarationStmt:
+			declareClass(stmt, env)
+		case ast.StructDeclStmt:
+			declareStruct(stmt, env)
+		}
+	}
+}
+
+func execVarDeclStmt(i *Interpreter, stmt ast.VarDeclStmt, env *Environment) {
+	var value Value = NullValue{}
+	if stmt.AssignedValue != nil {
+		value = convert(evalExpr(i, stmt.AssignedValue, env), stmt.ExplicitType)
+	}
+
+	if err := env.DeclareTyped(stmt.VariableName, value, stmt.IsConstant, stmt.ExplicitType); err != nil {
+		fail(stmt.Loc, "%s", err)
+	}
+}
+
+// endsLoop decides what the loop labelled label does after an iteration
+// that finished with result, reporting whether the loop stops and the
+// outcome it stops with. A break or continue naming no loop applies to the
+// innermost one; a return, or a break or continue naming an outer loop, is
+// passed on.
+func endsLoop(result outcome, label string) (outcome, bool) {
+	switch {
+	case result.flow == proceeding:
+		return normal, false
+	case result.flow == returning || result.label != "" && result.label != label:
+		return result, true
+	case result.flow == continuing:
+		return normal, false
+	default:
+		return normal, true
+	}
+}
+
+// execForStmt runs a C-style loop. The variables declared by Init live in
+// a scope of their own that every iteration shares.
+func execForStmt(i *Interpreter, stmt ast.ForStmt, env *Environment) outcome {
+	scope := NewEnvironment(env)
+	if stmt.Init != nil {
+		execStmt(i, stmt.Init, scope)
+	}
+
+	for stmt.Condition == nil || isTruthy(evalExpr(i, stmt.Condition, scope)) {
+		if result, done := endsLoop(execBody(i, stmt.Body, NewEnvironment(scope)), stmt.Label); done {
+			return result
+		}
+
+		if stmt.Post != nil {
+			evalExpr(i, stmt.Post, scope)
+		}
+	}
+
+	return normal
+}
+
+func execForeachStmt(i *Interpreter, stmt ast.ForeachStmt, env *Environment) outcome {
+	iterate := func(value, index Value) outcome {
+		scope := NewEnvironment(env)
+		declare(scope, stmt.Value, value, false, stmt.ValueLoc)
+		if stmt.Index != "" {
+			declare(scope, stmt.Index, index, false, stmt.IndexLoc)
+		}
+
+		return execBody(i, stmt.Body, scope)
+	}
+
+	if expr, ok := stmt.Iterable.(ast.RangeExpr); ok {
+		r := evalRange(i, expr, env)
+		for n := uint64(0); !r.empty; n++ {
+			if result, done := endsLoop(iterate(r.at(n), IntValue(n)), stmt.Label); done {
+				return result
+			}
+
+			if n == r.last {
+				break
+			}
+		}
+
+		return normal
+	}
+
+	switch iterable := evalExpr(i, stmt.Iterable, env).(type) {
+	case *ArrayValue:
+		for n, element := range iterable.Elements {
+			if result, done := endsLoop(iterate(element, IntValue(n)), stmt.Label); done {
+				return result
+			}
+		}
+	case StringValue:
+		// One character at a time; the index counts characters, not bytes
+		n := 0
+		for _, ch := range string(iterable) {
+			if result, done := endsLoop(iterate(StringValue(ch), IntValue(n)), stmt.Label); done {
+				return result
+			}
+
+			n++
+		}
+	case *ObjectValue:
+		// Keys in sorted order, so iteration is repeatable
+		for _, key := range slices.Sorted(maps.Keys(iterable.Fields)) {
+			if result, done := endsLoop(iterate(StringValue(key), iterable.Fields[key]), stmt.Label); done {
+				return result
+			}
+		}
+	default:
+		fail(stmt.Iterable.Span(), "cannot iterate over %s", typeName(iterable))
+	}
+
+	return normal
+}
+
+func declare(env *Environment, name string, value Value, constant bool, span lexer.Span) {
+	if err := env.Declare(name, value, constant); err != nil {
+		fail(span, "%s", err)
+	}
+}
+
+// declareClass declares the class stmt with its methods. Its static fields
+// stay null until execClassDeclStmt runs their initialisers.
+func declareClass(stmt ast.ClassDeclarationStmt, env *Environment) {
+	class := &ClassValue{
+		Name:       stmt.Name,
+		FieldTypes: map[string]ast.Type{},
+		Methods:    map[string]*FunctionValue{},
+		Static:     map[string]Value{},
+		Closure:    env,
+	}
+
+	declare(env, stmt.Name, class, true, stmt.Loc)
+
+	for _, field := range stmt.Fields {
+		class.FieldTypes[field.Name] = field.Type
+		if field.IsStatic {
+			class.Static[field.Name] = NullValue{}
+		} else {
+			class.Fields = append(class.Fields, field)
+		}
+	}
+
+	methods := stmt.Methods
+	if stmt.Constructor != nil {
+		methods = append(methods, *stmt.Constructor)
+	}
+
+	for _, method := range methods {
+		fn := &FunctionValue{
+			Name:       stmt.Name + "." + method.Name,
+			Parameters: method.Parameters,
+			ReturnType: method.ReturnType,
+			Body:       method.Body,
+			Closure:    env,
+		}
+
+		if method.IsStatic {
+			class.Static[method.Name] = fn
+		} else {
+			class.Methods[method.Name] = fn
+		}
+	}
+
+	if stmt.Constructor != nil {
+		class.Constructor = class.Methods[stmt.Constructor.Name]
+	}
+}
+
+// execClassDeclStmt runs the static field initialisers of a class that
+// hoist declared, in the order they are written.
+func execClassDeclStmt(i *Interpreter, stmt ast.ClassDeclarationStmt, env *Environment) {
+	value, _ := env.Lookup(stmt.Name)
+	class := value.(*ClassValue)
+	for _, field := range stmt.Fields {
+		if field.IsStatic && field.DefaultValue != nil {
+			class.Static[field.Name] = convert(evalExpr(i, field.DefaultValue, env), field.Type)
+		}
+	}
+}
+
+// declareStruct declares a struct. Structs share the class representation
+// at runtime: a ClassValue without a constructor whose instances are
+// created by struct instantiation expressions.
+func declareStruct(stmt ast.StructDeclStmt, env *Environment) {
+	structValue := &ClassValue{
+		Name:       stmt.StructName,
+		IsStruct:   true,
+		FieldTypes: map[string]ast.Type{},
+		Methods:    map[string]*FunctionValue{},
+		Static:     map[string]Value{},
+		Closure:    env,
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(stmt.Properties)) {
+		property := stmt.Properties[name]
+		structValue.FieldTypes[name] = property.Type
+		if property.IsStatic {
+			structValue.Static[name] = NullValue{}
+		} else {
+			structValue.Fields = append(structValue.Fields, ast.ClassField{Name: name, Type: property.Type, Loc: property.Loc})
+		}
+	}
+
+	for name, method := range stmt.Methods {
+		fn := &FunctionValue{
+			Name:       stmt.StructName + "." + name,
+			Parameters: method.Parameters,
+			ReturnType: method.ReturnType,
+			Body:       method.Body,
+			Closure:    env,
+		}
+
+		if method.IsStatic {
+			structValue.Static[name] = fn
+		} else {
+			structValue.Methods[name] = fn
+		}
+	}
+
+	declare(env, stmt.StructName, structValue, true, stmt.Loc)
+}
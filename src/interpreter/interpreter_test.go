@@ -0,0 +1,111 @@
+package interpreter
+
+import (
+	"custom_parser/src/lexer"
+	"custom_parser/src/parser"
+	"strings"
+	"testing"
+)
+
+// run executes src and returns what it printed, followed by the message of
+// the runtime error that stopped it, if any.
+func run(t *testing.T, src string) string {
+	t.Helper()
+	tokens, _ := lexer.Tokenize(src)
+	program, list := parser.Parse(tokens)
+	if len(list) > 0 {
+		t.Fatalf("%s: %v", src, list[0])
+	}
+
+	var out strings.Builder
+	if err := New(&out).Run(program); err != nil {
+		out.WriteString(err.(RuntimeError).Message)
+	}
+
+	return out.String()
+}
+
+func TestHoisting(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`greet(); fn greet() { println("hi"); }`, "hi\n"},
+		{"fn even(n: int): boolean { if n == 0 { return true; } return odd(n - 1); } fn odd(n: int): boolean { if n == 0 { return false; } return even(n - 1); } println(even(10));", "true\n"},
+		{"fn f(): int { return g(); fn g(): int { return 7; } } println(f());", "7\n"},
+		{"{ println(g()); fn g(): int { return 1; } }", "1\n"},
+		{"let p = P{x: 1}; println(p.x); struct P { x: int; }", "1\n"},
+		{"let c = new C(); println(c.f()); class C { fn f(): int { return 2; } }", "2\n"},
+		// Static initialisers still run where the class is declared
+		{"println(C.n); let k = 3; class C { static let n = k; } println(C.n);", "null\n3\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestReturns(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"fn sign(n: int): int { if n > 0 { return 1; } return -1; } println(sign(5), sign(-5));", "1 -1\n"},
+		{"fn f() { return; } println(f());", "null\n"},
+		// The final expression statement is the result when nothing is returned
+		{"fn neg(n: int): int { -n; } println(neg(2));", "-2\n"},
+		{"let add = fn(a: int, b: int): int { a + b; }; println(add(1, 2));", "3\n"},
+		{"fn f() { let x = 1; } println(f());", "null\n"},
+		{"fn f() { 1; let x = 2; } println(f());", "null\n"},
+		// A return leaves every enclosing block and loop of the function
+		{"fn find(): int { foreach x in [1, 2, 3] { while true { if x == 2 { return x; } break; } } return 0; } println(find());", "2\n"},
+		{`fn f() { { return; } println("after"); } f(); println("done");`, "done\n"},
+		{"fn outer(): int { let inner = fn(): int { return 1; }; inner(); return 2; } println(outer());", "2\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"let i = 0; while i < 3 { print(i); i += 1; } println();", "012\n"},
+		{"for let i = 0; i < 3; i += 1 { print(i); } println();", "012\n"},
+		{"for let i = 0; ; i += 1 { if i == 2 { break; } print(i); } println();", "01\n"},
+		{"for let i = 0; i < 5; i += 1 { if i % 2 == 0 { continue; } print(i); } println();", "13\n"},
+		// continue still runs the post statement of a for loop
+		{"let n = 0; for let i = 0; i < 3; i += 1 { n += 1; continue; } println(n);", "3\n"},
+		{"let i = 10; while i < 3 { i += 1; } println(i);", "10\n"},
+		// Each iteration has its own scope, the loop variable is shared
+		{"let fs = []; for let i = 0; i < 2; i += 1 { let j = i; fs.push(fn(): int { j; }); } println(fs[0](), fs[1]());", "0 1\n"},
+		{"outer: for let i = 0; i < 3; i += 1 { for let j = 0; j < 3; j += 1 { if j == 1 { continue outer; } if i == 2 { break outer; } print(`${i}${j} `); } } println();", "00 10 \n"},
+		{"outer: while true { while true { break outer; } } println(\"out\");", "out\n"},
+		{"let n = 0; outer: foreach x in 0..3 { foreach y in 0..3 { if y > x { continue outer; } n += 1; } } println(n);", "6\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestForeach(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"foreach x in [4, 5] { print(x); } println();", "45\n"},
+		{`foreach x, i in ["a", "b"] { print(i, x, ""); } println();`, "0 a 1 b \n"},
+		// Strings walk characters and count them, not bytes
+		{`foreach ch, i in "héy" { print(i, ch, ""); } println();`, "0 h 1 é 2 y \n"},
+		{"foreach n, i in 5..=7 { print(i * n, \"\"); } println();", "0 6 14 \n"},
+		// Maps walk their keys in sorted order, with the value second
+		{"import time; foreach key, value in time { print(key, \"\"); } println();", "hours now second \n"},
+		{"import time; foreach key, value in time { if key == \"second\" { println(value); } }", "1000\n"},
+		{"let xs = [1, 2]; foreach x in xs { x = 0; } println(xs);", "[1, 2]\n"},
+		{"foreach x in 1 {}", "cannot iterate over int"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package interpreter
+
+import (
+	"custom_parser/src/ast"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Value interface {
+	value()
+	String() string
+}
+
+type NullValue struct{}
+
+func (v NullValue) value()         {}
+func (v NullValue) String() string { return "null" }
+
+type IntValue int64
+
+func (v IntValue) value()         {}
+func (v IntValue) String() string { return strconv.FormatInt(int64(v), 10) }
+
+type FloatValue float64
+
+func (v FloatValue) value()         {}
+func (v FloatValue) String() string { return strconv.FormatFloat(float64(v), 'f', -1, 64) }
+
+type StringValue string
+
+func (v StringValue) value()         {}
+func (v StringValue) String() string { return string(v) }
+
+type BooleanValue bool
+
+func (v BooleanValue) value()         {}
+func (v BooleanValue) String() string { return strconv.FormatBool(bool(v)) }
+
+// ArrayValue is shared by reference, so element writes through one binding
+// are visible through every other.
+type ArrayValue struct {
+	Elements []Value
+}
+
+func (v *ArrayValue) value() {}
+func (v *ArrayValue) String() string {
+	parts := make([]string, len(v.Elements))
+	for i, element := range v.Elements {
+		parts[i] = element.String()
+	}
+
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// ObjectValue backs native modules.
+type ObjectValue struct {
+	Name   string
+	Fields map[string]Value
+}
+
+func (v *ObjectValue) value()         {}
+func (v *ObjectValue) String() string { return fmt.Sprintf("<object %s>", v.Name) }
+
+// FunctionValue is a user defined function together with the environment it
+// closes over.
+type FunctionValue struct {
+	Name       string
+	Parameters []ast.Parameter
+	ReturnType ast.Type // nil when not declared
+	Body       []ast.Stmt
+	Closure    *Environment
+}
+
+func (v *FunctionValue) value() {}
+func (v *FunctionValue) String() string {
+	if v.Name == "" {
+		return "<fn>"
+	}
+
+	return fmt.Sprintf("<fn %s>", v.Name)
+}
+
+// ClassValue describes both classes and structs; IsStruct tells them apart.
+type ClassValue struct {
+	Name        string
+	IsStruct    bool
+	Fields      []ast.ClassField    // instance fields, initialised by new or a struct literal
+	FieldTypes  map[string]ast.Type // declared type of every field, static or not
+	Methods     map[string]*FunctionValue
+	Static      map[string]Value
+	Constructor *FunctionValue
+	Closure     *Environment
+}
+
+func (v *ClassValue) value()         {}
+func (v *ClassValue) String() string { return fmt.Sprintf("<class %s>", v.Name) }
+
+type InstanceValue struct {
+	Class  *ClassValue
+	Fields map[string]Value
+}
+
+func (v *InstanceValue) value()         {}
+func (v *InstanceValue) String() string { return fmt.Sprintf("<%s instance>", v.Class.Name) }
+
+type NativeFunction struct {
+	Name string
+	Fn   func(args []Value) (Value, error)
+}
+
+func (v NativeFunction) value()         {}
+func (v NativeFunction) String() string { return fmt.Sprintf("<native fn %s>", v.Name) }
+
+// typeName is used in runtime error messages.
+func typeName(v Value) string {
+	switch v := v.(type) {
+	case NullValue:
+		return "null"
+	case IntValue:
+		return "int"
+	case FloatValue:
+		return "float"
+	case StringValue:
+		return "string"
+	case BooleanValue:
+		return "boolean"
+	case *ArrayValue:
+		return "array"
+	case *ObjectValue:
+		return "object"
+	case *ClassValue:
+		return "class"
+	case *InstanceValue:
+		return v.Class.Name
+	case *FunctionValue, NativeFunction:
+		return "function"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// convert returns value as stored in a slot declared with type t. The
+// checker lets an int stand in for a float, so an int stored where a float
+// is declared becomes one.
+func convert(value Value, t ast.Type) Value {
+	if symbol, ok := t.(ast.SymbolType); ok && symbol.Name == "float" {
+		if n, ok := value.(IntValue); ok {
+			return FloatValue(n)
+		}
+	}
+
+	return value
+}
+
+// isTruthy treats false and null as false and every other value as true.
+func isTruthy(v Value) bool {
+	switch v := v.(type) {
+	case BooleanValue:
+		return bool(v)
+	case NullValue:
+		return false
+	default:
+		return true
+	}
+}
+
+func valuesEqual(a, b Value) bool {
+	switch a := a.(type) {
+	case NullValue:
+		_, ok := b.(NullValue)
+		return ok
+	case IntValue:
+		// Two ints compare exactly; widening both to float would make
+		// large neighbouring ints equal
+		if other, ok := b.(IntValue); ok {
+			return a == other
+		}
+
+		// An int and a float compare by value, so 1 == 1.0
+		r, ok := b.(FloatValue)
+		return ok && float64(a) == float64(r)
+	case FloatValue:
+		r, ok := toFloat(b)
+		return ok && float64(a) == r
+	case StringValue, BooleanValue:
+		return a == b
+	case *ArrayValue:
+		other, ok := b.(*ArrayValue)
+		return ok && a == other
+	case *ObjectValue:
+		other, ok := b.(*ObjectValue)
+		return ok && a == other
+	case *FunctionValue:
+		other, ok := b.(*FunctionValue)
+		return ok && a == other
+	case *ClassValue:
+		other, ok := b.(*ClassValue)
+		return ok && a == other
+	case *InstanceValue:
+		other, ok := b.(*InstanceValue)
+		return ok && a == other
+	default:
+		return false
+	}
+}
+
+// toFloat converts an int or a float to float64.
+func toFloat(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case IntValue:
+		return float64(v), true
+	case FloatValue:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,41 @@
+package interpreter
+
+import "testing"
+
+func TestEquality(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"println(1 == 1, 1 == 2, 1 != 2);", "true false true\n"},
+		{"println(9007199254740993 == 9007199254740992, 9007199254740993 != 9007199254740992);", "false true\n"},
+		{"println(1 == 1.0, 1.0 == 1, 1 == 1.5, 2.5 != 2);", "true true false true\n"},
+		{"println(0.5 == 0.5, 0.5 == 0.25);", "true false\n"},
+		{`println(1 == "1", null == null, 0 == null, "a" == "a");`, "false true false true\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"const ok = true; let no = false; println(ok, no, null);", "true false null\n"},
+		{"println(!true, !false, !null);", "false true true\n"},
+		// Only false and null are falsy
+		{`println(!0, !"", ![]);`, "false false false\n"},
+		{`if null { println("yes"); } else { println("no"); }`, "no\n"},
+		{`if 0 { println("yes"); }`, "yes\n"},
+		{"println(typeof true, typeof null);", "boolean null\n"},
+		{"let x: int; println(x, x == null);", "null true\n"},
+		{"println(true && false, true || false, false == false);", "false true true\n"},
+		// && and || stop at the first operand that decides the result
+		{"fn f(): boolean { println(\"called\"); return true; } println(false && f(), true || f());", "false true\n"},
+	}
+
+	for _, test := range tests {
+		if got := run(t, test.src); got != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package lexer
+
+import "fmt"
+
+type ErrorKind int
+
+const (
+	UnexpectedCharacter ErrorKind = iota
+	UnterminatedString
+	UnterminatedComment
+	InvalidEscape
+	InvalidNumber
+	InvalidUTF8
+)
+
+// Error describes a lexical error. The lexer records one for every ILLEGAL
+// token it emits and keeps scanning.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Span    Span
+}
+
+func (err Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.Span.Start, err.Message)
+}
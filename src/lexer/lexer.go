@@ -2,22 +2,60 @@
 package lexer
 
 import (
+	"cmp"
 	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type lexer struct {
+	file   string
 	source string
 	pos    int
 	line   int
-	Tokens []Token
+	column int      // column of pos on the current line, counted in runes
+	start  Position // position of the first character of the token being scanned
+	mode   Mode
+
+	// templates holds, for each ${ interpolation being scanned, how many
+	// of its own braces are open, so the } closing it can be told apart.
+	templates []int
+	Tokens    []Token
+	Errors    []Error
 }
 
-func Tokenize(source string) []Token {
+// Tokenize never fails outright: malformed input is emitted as ILLEGAL
+// tokens and every lexical error found is returned alongside the tokens.
+func Tokenize(source string) ([]Token, []Error) {
+	return TokenizeFile("", source)
+}
+
+// TokenizeFile behaves like Tokenize but records file as the origin of
+// every token's span.
+func TokenizeFile(file string, source string) ([]Token, []Error) {
+	return TokenizeMode(file, source, 0)
+}
+
+// Mode selects optional lexer behaviour.
+type Mode uint
+
+const (
+	// KeepComments emits every comment as a COMMENT token instead of
+	// discarding it like whitespace.
+	KeepComments Mode = 1 << iota
+)
+
+// TokenizeMode behaves like TokenizeFile with the behaviour selected by mode.
+func TokenizeMode(file string, source string, mode Mode) ([]Token, []Error) {
 	lex := &lexer{
+		file:   file,
 		source: source,
 		pos:    0,
 		line:   1,
+		column: 1,
+		mode:   mode,
 		Tokens: make([]Token, 0),
 	}
 
@@ -25,15 +63,23 @@ func Tokenize(source string) []Token {
 		lex.scanToken()
 	}
 
+	lex.start = lex.position()
 	lex.push(newUniqueToken(EOF, "EOF"))
-	return lex.Tokens
+	return lex.Tokens, lex.Errors
 }
 
 func (lex *lexer) scanToken() {
+	lex.start = lex.position()
 	ch := lex.peek()
 
+	if lex.atInvalidUTF8() {
+		lex.advance()
+		lex.illegal(InvalidUTF8, fmt.Sprintf("invalid UTF-8 byte %#x", lex.source[lex.start.Offset]))
+		return
+	}
+
 	// Skip whitespace
-	if unicode.IsSpace(rune(ch)) {
+	if unicode.IsSpace(ch) {
 		lex.skipWhitespace()
 		return
 	}
@@ -44,20 +90,32 @@ func (lex *lexer) scanToken() {
 		return
 	}
 
+	if ch == '/' && lex.peekNext() == '*' {
+		lex.skipBlockComment()
+		return
+	}
+
 	// String literals
 	if ch == '"' {
 		lex.scanString()
 		return
 	}
 
+	if ch == '`' {
+		lex.advance()
+		lex.push(newUniqueToken(BACKTICK, "`"))
+		lex.scanTemplateText()
+		return
+	}
+
 	// Numbers
-	if unicode.IsDigit(rune(ch)) {
+	if isDigit(ch) {
 		lex.scanNumber()
 		return
 	}
 
 	// Identifiers and keywords
-	if unicode.IsLetter(rune(ch)) || ch == '_' {
+	if isIdentifierStart(ch) {
 		lex.scanIdentifier()
 		return
 	}
@@ -93,6 +151,12 @@ func (lex *lexer) scanToken() {
 			lex.push(newUniqueToken(LESS_EQUALS, "<="))
 			return
 		}
+		if lex.peekNext() == '<' {
+			lex.advance()
+			lex.advance()
+			lex.push(newUniqueToken(SHIFT_LEFT, "<<"))
+			return
+		}
 		lex.advance()
 		lex.push(newUniqueToken(LESS, "<"))
 		return
@@ -104,6 +168,12 @@ func (lex *lexer) scanToken() {
 			lex.push(newUniqueToken(GREATER_EQUALS, ">="))
 			return
 		}
+		if lex.peekNext() == '>' {
+			lex.advance()
+			lex.advance()
+			lex.push(newUniqueToken(SHIFT_RIGHT, ">>"))
+			return
+		}
 		lex.advance()
 		lex.push(newUniqueToken(GREATER, ">"))
 		return
@@ -115,6 +185,9 @@ func (lex *lexer) scanToken() {
 			lex.push(newUniqueToken(OR, "||"))
 			return
 		}
+		lex.advance()
+		lex.push(newUniqueToken(PIPE, "|"))
+		return
 
 	case '&':
 		if lex.peekNext() == '&' {
@@ -123,8 +196,18 @@ func (lex *lexer) scanToken() {
 			lex.push(newUniqueToken(AND, "&&"))
 			return
 		}
+		lex.advance()
+		lex.push(newUniqueToken(AMPERSAND, "&"))
+		return
 
 	case '.':
+		if lex.peekNext() == '.' && lex.peekAhead(2) == '=' {
+			lex.advance()
+			lex.advance()
+			lex.advance()
+			lex.push(newUniqueToken(DOT_DOT_EQUALS, "..="))
+			return
+		}
 		if lex.peekNext() == '.' {
 			lex.advance()
 			lex.advance()
@@ -171,17 +254,10 @@ func (lex *lexer) scanToken() {
 		lex.push(newUniqueToken(DASH, "-"))
 		return
 
-	// case '?':
-	// 	if lex.peekNext() == '?' && lex.peekAhead(2) == '=' {
-	// 		lex.advance()
-	// 		lex.advance()
-	// 		lex.advance()
-	// 		lex.push(newUniqueToken(NULLISH_ASSIGNMENT, "??="))
-	// 		return
-	// 	}
-	// 	lex.advance()
-	// 	lex.push(newUniqueToken(QUESTION, "?"))
-	// 	return
+	case '?':
+		lex.advance()
+		lex.push(newUniqueToken(QUESTION, "?"))
+		return
 
 	// Single-character tokens
 	case '[':
@@ -193,12 +269,27 @@ func (lex *lexer) scanToken() {
 		lex.push(newUniqueToken(CLOSE_BRACKET, "]"))
 		return
 	case '{':
+		if len(lex.templates) > 0 {
+			lex.templates[len(lex.templates)-1]++
+		}
+
 		lex.advance()
 		lex.push(newUniqueToken(OPEN_CURLY, "{"))
 		return
 	case '}':
 		lex.advance()
 		lex.push(newUniqueToken(CLOSE_CURLY, "}"))
+
+		if depth := len(lex.templates) - 1; depth >= 0 {
+			if lex.templates[depth] == 0 {
+				// The interpolation is over; back to the template's text
+				lex.templates = lex.templates[:depth]
+				lex.scanTemplateText()
+			} else {
+				lex.templates[depth]--
+			}
+		}
+
 		return
 	case '(':
 		lex.advance()
@@ -232,58 +323,297 @@ func (lex *lexer) scanToken() {
 		lex.advance()
 		lex.push(newUniqueToken(PERCENT, "%"))
 		return
+	case '^':
+		lex.advance()
+		lex.push(newUniqueToken(CARET, "^"))
+		return
+	case '~':
+		lex.advance()
+		lex.push(newUniqueToken(TILDE, "~"))
+		return
 	}
 
-	panic(fmt.Sprintf("lexer error: unexpected character '%c' at position %d", ch, lex.pos))
+	lex.advance()
+	lexeme := lex.source[lex.start.Offset:lex.pos]
+	lex.illegal(UnexpectedCharacter, fmt.Sprintf("unexpected character %q", lexeme))
 }
 
+// scanString scans a double quoted string. The token value is the decoded
+// content, without quotes; Raw keeps the lexeme as written.
 func (lex *lexer) scanString() {
-	start := lex.pos
+	// Kept so an unterminated string can be rescanned as a single line
+	saved := lex.mark()
 	lex.advance() // Skip opening quote
 
+	var value strings.Builder
 	for !lex.atEOF() && lex.peek() != '"' {
-		lex.advance()
+		switch {
+		case lex.peek() == '\\':
+			lex.scanEscape(&value)
+		case lex.atInvalidUTF8():
+			lex.skipInvalidUTF8()
+		default:
+			value.WriteRune(lex.peek())
+			lex.advance()
+		}
 	}
 
 	if lex.atEOF() {
-		panic("lexer error: unterminated string literal")
+		// Resume scanning on the next line rather than swallowing the
+		// rest of the file.
+		lex.reset(saved)
+		for !lex.atEOF() && lex.peek() != '\n' {
+			lex.advance()
+		}
+
+		lex.illegal(UnterminatedString, "unterminated string literal")
+		return
 	}
 
 	lex.advance() // Skip closing quote
-	value := lex.source[start:lex.pos]
-	lex.push(newUniqueToken(STRING, value))
+	lex.push(newUniqueToken(STRING, value.String()))
 }
 
-func (lex *lexer) scanNumber() {
-	start := lex.pos
+// scanTemplateText scans template string text up to the next ${ or the
+// closing backtick. A TEMPLATE_STRING token is produced even for empty
+// text, so text and interpolations always alternate.
+func (lex *lexer) scanTemplateText() {
+	lex.start = lex.position()
+	var value strings.Builder
+	for !lex.atEOF() && lex.peek() != '`' && !(lex.peek() == '$' && lex.peekNext() == '{') {
+		switch {
+		case lex.peek() == '\\' && (lex.peekNext() == '`' || lex.peekNext() == '$'):
+			lex.advance()
+			value.WriteRune(lex.peek())
+			lex.advance()
+		case lex.peek() == '\\':
+			lex.scanEscape(&value)
+		case lex.atInvalidUTF8():
+			lex.skipInvalidUTF8()
+		default:
+			value.WriteRune(lex.peek())
+			lex.advance()
+		}
+	}
+
+	if lex.atEOF() {
+		lex.illegal(UnterminatedString, "unterminated template string")
+		return
+	}
+
+	lex.push(newUniqueToken(TEMPLATE_STRING, value.String()))
+	lex.start = lex.position()
+	if lex.peek() == '`' {
+		lex.advance()
+		lex.push(newUniqueToken(BACKTICK, "`"))
+		return
+	}
+
+	lex.advance()
+	lex.advance()
+	lex.push(newUniqueToken(DOLLAR_CURLY, "${"))
+	lex.templates = append(lex.templates, 0)
+}
+
+var escapes = map[rune]rune{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+}
+
+// scanEscape decodes the escape sequence at the current backslash into
+// value. An invalid sequence is reported and left out of the value.
+func (lex *lexer) scanEscape(value *strings.Builder) {
+	start := lex.position()
+	lex.advance() // Skip the backslash
 
-	// Scan integer part
-	for !lex.atEOF() && unicode.IsDigit(rune(lex.peek())) {
+	if decoded, ok := escapes[lex.peek()]; ok {
+		value.WriteRune(decoded)
 		lex.advance()
+		return
 	}
 
-	// Check for decimal part
-	if !lex.atEOF() && lex.peek() == '.' && unicode.IsDigit(rune(lex.peekNext())) {
-		lex.advance() // Skip '.'
-		for !lex.atEOF() && unicode.IsDigit(rune(lex.peek())) {
+	if lex.peek() != 'u' {
+		if !lex.atEOF() && lex.peek() != '\n' {
 			lex.advance()
 		}
+
+		sequence := lex.source[start.Offset:lex.pos]
+		lex.report(InvalidEscape, fmt.Sprintf("invalid escape sequence %q", sequence), Span{Start: start, End: lex.position()})
+		return
 	}
 
-	value := lex.source[start:lex.pos]
-	lex.push(newUniqueToken(NUMBER, value))
+	// \u{XXXX}: one to six hex digits naming a Unicode scalar value
+	lex.advance()
+	digits := ""
+	if lex.peek() == '{' {
+		lex.advance()
+		for isHexDigit(lex.peek()) {
+			lex.advance()
+		}
+
+		digits = lex.source[start.Offset+3 : lex.pos]
+	}
+
+	if lex.peek() != '}' || digits == "" || len(digits) > 6 {
+		// Take the closing brace too, so none of the sequence ends up in
+		// the value
+		if lex.peek() == '}' {
+			lex.advance()
+		}
+
+		lex.report(InvalidEscape, `invalid unicode escape, expected \u{...} with 1 to 6 hex digits`, Span{Start: start, End: lex.position()})
+		return
+	}
+
+	lex.advance() // Skip closing brace
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	if code > unicode.MaxRune || code >= 0xD800 && code <= 0xDFFF {
+		sequence := lex.source[start.Offset:lex.pos]
+		lex.report(InvalidEscape, fmt.Sprintf("%s is not a valid unicode code point", sequence), Span{Start: start, End: lex.position()})
+		return
+	}
+
+	value.WriteRune(rune(code))
 }
 
-func (lex *lexer) scanIdentifier() {
-	start := lex.pos
+func isHexDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
 
-	for !lex.atEOF() {
-		ch := lex.peek()
-		if unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) || ch == '_' {
+// scanNumber scans a decimal literal, optionally with a fraction and an
+// exponent, or a 0x, 0b or 0o prefixed integer. Underscores may separate
+// digits; they are removed from the token value but kept in Raw.
+func (lex *lexer) scanNumber() {
+	base := 10
+	if lex.peek() == '0' {
+		switch lex.peekNext() {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+
+		if base != 10 {
 			lex.advance()
+			lex.advance()
+		}
+	}
+
+	problem := lex.scanDigits(base)
+	if base == 10 {
+		if lex.peek() == '.' && isDigit(lex.peekNext()) {
+			lex.advance() // Skip '.'
+			problem = cmp.Or(problem, lex.scanDigits(base))
+		}
+
+		if lex.peek() == 'e' || lex.peek() == 'E' {
+			lex.advance()
+			if lex.peek() == '+' || lex.peek() == '-' {
+				lex.advance()
+			}
+
+			problem = cmp.Or(problem, lex.scanDigits(base))
+		}
+	}
+
+	// A literal running straight into letters, as in 12px or 0b102, is
+	// reported as a whole rather than split into two tokens.
+	if isIdentifierPart(lex.peek()) {
+		for isIdentifierPart(lex.peek()) {
+			lex.advance()
+		}
+
+		problem = cmp.Or(problem, "invalid suffix")
+	}
+
+	literal := lex.source[lex.start.Offset:lex.pos]
+	if problem != "" {
+		lex.illegal(InvalidNumber, fmt.Sprintf("invalid number literal %s: %s", literal, problem))
+		return
+	}
+
+	lex.push(newUniqueToken(NUMBER, strings.ReplaceAll(literal, "_", "")))
+}
+
+// scanDigits consumes digits of the given base and the underscores between
+// them, describing what is wrong with them, if anything.
+func (lex *lexer) scanDigits(base int) string {
+	problem := ""
+	digits := 0
+	var last rune
+	for {
+		ch := lex.peek()
+		if ch == '_' {
+			if digits == 0 || last == '_' {
+				problem = cmp.Or(problem, "'_' must separate digits")
+			}
+		} else if value := digitValue(ch); value < 10 || value < base {
+			if value >= base {
+				problem = cmp.Or(problem, fmt.Sprintf("invalid digit %q in base %d", ch, base))
+			}
+
+			digits++
 		} else {
 			break
 		}
+
+		last = ch
+		lex.advance()
+	}
+
+	if digits == 0 {
+		return cmp.Or(problem, "missing digits")
+	}
+
+	if last == '_' {
+		return cmp.Or(problem, "'_' must separate digits")
+	}
+
+	return problem
+}
+
+// digitValue is the value of ch as a digit, or 36 when it is not one.
+func digitValue(ch rune) int {
+	switch {
+	case isDigit(ch):
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch-'A') + 10
+	default:
+		return 36
+	}
+}
+
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// Identifiers start with a Unicode letter (category L) or an underscore and
+// continue with letters, decimal digits (Nd), combining marks (Mn and Mc) and
+// underscores, so café, λ and _x1 are all identifiers. Identifiers are
+// compared as written: no Unicode normalization is applied, so a precomposed
+// é and e followed by a combining acute accent name different variables.
+// Number literals only ever use the ASCII digits.
+func isIdentifierStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isIdentifierPart(ch rune) bool {
+	return isIdentifierStart(ch) || unicode.Is(unicode.Nd, ch) || unicode.In(ch, unicode.Mn, unicode.Mc)
+}
+
+func (lex *lexer) scanIdentifier() {
+	start := lex.pos
+
+	for isIdentifierPart(lex.peek()) {
+		lex.advance()
 	}
 
 	value := lex.source[start:lex.pos]
@@ -297,52 +627,167 @@ func (lex *lexer) scanIdentifier() {
 }
 
 func (lex *lexer) skipWhitespace() {
-	for !lex.atEOF() && unicode.IsSpace(rune(lex.peek())) {
-		if lex.peek() == '\n' {
-			lex.line++
-		}
+	for !lex.atEOF() && unicode.IsSpace(lex.peek()) {
 		lex.advance()
 	}
 }
 
 func (lex *lexer) skipComment() {
-	// Skip until end of line
+	// Skip until end of line, leaving the newline to skipWhitespace
 	for !lex.atEOF() && lex.peek() != '\n' {
-		lex.advance()
+		if lex.atInvalidUTF8() {
+			lex.skipInvalidUTF8()
+		} else {
+			lex.advance()
+		}
 	}
-	if !lex.atEOF() {
-		lex.advance() // Skip the newline
-		lex.line++
+
+	text := strings.TrimRight(lex.source[lex.start.Offset:lex.pos], " \t\r")
+	if strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////") {
+		lex.push(newUniqueToken(DOC_COMMENT, text))
+	} else if lex.mode&KeepComments != 0 {
+		lex.push(newUniqueToken(COMMENT, text))
 	}
 }
 
-// Helper methods
-func (lex *lexer) peek() byte {
-	if lex.atEOF() {
-		return 0
+// skipBlockComment skips a /* */ comment. Block comments nest, so a
+// commented out region may itself contain block comments.
+func (lex *lexer) skipBlockComment() {
+	depth := 0
+	for !lex.atEOF() {
+		switch {
+		case lex.peek() == '/' && lex.peekNext() == '*':
+			depth++
+			lex.advance()
+		case lex.peek() == '*' && lex.peekNext() == '/':
+			depth--
+			lex.advance()
+		case lex.atInvalidUTF8():
+			lex.skipInvalidUTF8()
+			continue
+		}
+
+		lex.advance()
+		if depth == 0 {
+			if lex.mode&KeepComments != 0 {
+				lex.push(newUniqueToken(COMMENT, lex.source[lex.start.Offset:lex.pos]))
+			}
+
+			return
+		}
 	}
-	return lex.source[lex.pos]
+
+	lex.illegal(UnterminatedComment, "unterminated block comment")
 }
 
-func (lex *lexer) peekNext() byte {
-	if lex.pos+1 >= len(lex.source) {
-		return 0
-	}
-	return lex.source[lex.pos+1]
+// Helper methods
+
+// peek decodes the rune at the current position. It returns 0 at the end of
+// the source and utf8.RuneError for a byte that is not valid UTF-8.
+func (lex *lexer) peek() rune {
+	return lex.peekAhead(0)
+}
+
+func (lex *lexer) peekNext() rune {
+	return lex.peekAhead(1)
 }
 
-func (lex *lexer) peekAhead(n int) byte {
-	if lex.pos+n >= len(lex.source) {
+// peekAhead decodes the rune n runes past the current position.
+func (lex *lexer) peekAhead(n int) rune {
+	pos := lex.pos
+	for ; n > 0 && pos < len(lex.source); n-- {
+		_, size := utf8.DecodeRuneInString(lex.source[pos:])
+		pos += size
+	}
+
+	if pos >= len(lex.source) {
 		return 0
 	}
-	return lex.source[lex.pos+n]
+
+	ch, _ := utf8.DecodeRuneInString(lex.source[pos:])
+	return ch
 }
 
+// advance moves past the current rune, or past a single byte of invalid
+// UTF-8, which counts as one column.
 func (lex *lexer) advance() {
-	lex.pos++
+	if lex.atEOF() {
+		return
+	}
+
+	ch, size := utf8.DecodeRuneInString(lex.source[lex.pos:])
+	if ch == '\n' {
+		lex.line++
+		lex.column = 1
+	} else {
+		lex.column++
+	}
+	lex.pos += size
+}
+
+// atInvalidUTF8 reports whether the current byte does not begin a valid
+// UTF-8 encoding. A correctly encoded U+FFFD is not invalid.
+func (lex *lexer) atInvalidUTF8() bool {
+	ch, size := utf8.DecodeRuneInString(lex.source[lex.pos:])
+	return ch == utf8.RuneError && size == 1
+}
+
+// skipInvalidUTF8 reports the invalid byte at the current position and moves
+// past it, for strings and comments that carry on regardless.
+func (lex *lexer) skipInvalidUTF8() {
+	start := lex.position()
+	invalid := lex.source[lex.pos]
+	lex.advance()
+	lex.report(InvalidUTF8, fmt.Sprintf("invalid UTF-8 byte %#x", invalid), Span{Start: start, End: lex.position()})
+}
+
+// illegal emits an ILLEGAL token for everything scanned since the start of
+// the current token and records the matching error.
+func (lex *lexer) illegal(kind ErrorKind, message string) {
+	lex.push(newUniqueToken(ILLEGAL, lex.source[lex.start.Offset:lex.pos]))
+	lex.report(kind, message, lex.Tokens[len(lex.Tokens)-1].Span)
+}
+
+// report records an error without emitting a token, for mistakes that do
+// not stop the surrounding token from being produced.
+func (lex *lexer) report(kind ErrorKind, message string, span Span) {
+	lex.Errors = append(lex.Errors, Error{
+		Kind:    kind,
+		Message: message,
+		Span:    span,
+	})
+}
+
+// mark saves the scanning position so that reset can return to it. Errors
+// reported in between are discarded.
+type mark struct {
+	pos, line, column, errors int
+}
+
+func (lex *lexer) mark() mark {
+	return mark{lex.pos, lex.line, lex.column, len(lex.Errors)}
+}
+
+func (lex *lexer) reset(m mark) {
+	lex.pos, lex.line, lex.column = m.pos, m.line, m.column
+	lex.Errors = lex.Errors[:m.errors]
+}
+
+// position reports where the lexer currently is in the source.
+func (lex *lexer) position() Position {
+	return Position{
+		File:   lex.file,
+		Line:   lex.line,
+		Column: lex.column,
+		Offset: lex.pos,
+	}
 }
 
+// push appends token, spanning from the start of the current scan up to
+// the lexer's current position.
 func (lex *lexer) push(token Token) {
+	token.Span = Span{Start: lex.start, End: lex.position()}
+	token.Raw = lex.source[lex.start.Offset:lex.pos]
 	lex.Tokens = append(lex.Tokens, token)
 }
 
@@ -0,0 +1,290 @@
+package lexer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	tokens, _ := Tokenize("let a = 1;\n  a += 22;")
+	want := []struct {
+		value              string
+		line, column, from int
+		to                 int // offset just past the token
+	}{
+		{"let", 1, 1, 0, 3},
+		{"a", 1, 5, 4, 5},
+		{"=", 1, 7, 6, 7},
+		{"1", 1, 9, 8, 9},
+		{";", 1, 10, 9, 10},
+		{"a", 2, 3, 13, 14},
+		{"+=", 2, 5, 15, 17},
+		{"22", 2, 8, 18, 20},
+		{";", 2, 10, 20, 21},
+	}
+
+	if len(tokens) != len(want)+1 {
+		t.Fatalf("got %d tokens, want %d and EOF", len(tokens), len(want))
+	}
+
+	for i, w := range want {
+		token := tokens[i]
+		start := token.Span.Start
+		if token.Value != w.value || start.Line != w.line || start.Column != w.column || start.Offset != w.from || token.Span.End.Offset != w.to {
+			t.Errorf("token %d: got %q at %d:%d, offsets %d-%d; want %q at %d:%d, offsets %d-%d",
+				i, token.Value, start.Line, start.Column, start.Offset, token.Span.End.Offset,
+				w.value, w.line, w.column, w.from, w.to)
+		}
+	}
+
+	if eof := tokens[len(tokens)-1]; eof.Kind != EOF || eof.Span.Start.Offset != 21 {
+		t.Errorf("got %s at offset %d, want EOF at 21", TokenKindString(eof.Kind), eof.Span.Start.Offset)
+	}
+}
+
+// kinds lists the kinds of tokens, leaving out the final EOF.
+func kinds(tokens []Token) []TokenKind {
+	list := make([]TokenKind, 0, len(tokens))
+	for _, token := range tokens[:len(tokens)-1] {
+		list = append(list, token.Kind)
+	}
+
+	return list
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []TokenKind
+		errors []ErrorKind
+	}{
+		{"a @ b", []TokenKind{IDENTIFIER, ILLEGAL, IDENTIFIER}, []ErrorKind{UnexpectedCharacter}},
+		{`"abc`, []TokenKind{ILLEGAL}, []ErrorKind{UnterminatedString}},
+		{"a /* abc", []TokenKind{IDENTIFIER, ILLEGAL}, []ErrorKind{UnterminatedComment}},
+		// Scanning carries on after every error
+		{"@ a # b", []TokenKind{ILLEGAL, IDENTIFIER, ILLEGAL, IDENTIFIER}, []ErrorKind{UnexpectedCharacter, UnexpectedCharacter}},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if got := kinds(tokens); !slices.Equal(got, test.tokens) {
+			t.Errorf("%q: got tokens %v, want %v", test.src, got, test.tokens)
+		}
+
+		var got []ErrorKind
+		for _, err := range errors {
+			got = append(got, err.Kind)
+		}
+
+		if !slices.Equal(got, test.errors) {
+			t.Errorf("%q: got errors %v, want %v", test.src, got, test.errors)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		src, value string
+		errors     int
+	}{
+		{`"plain"`, "plain", 0},
+		{`"a\tb\nc\\d\"e\r"`, "a\tb\nc\\d\"e\r", 0},
+		{`"\u{48}\u{1F600}"`, "H\U0001F600", 0},
+		// Invalid escapes are reported and left out of the value
+		{`"a\qb"`, "ab", 1},
+		{`"\u{}"`, "", 1},
+		{`"\u{D800}"`, "", 1},
+		{`"\u{1234567}"`, "", 1},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		token := tokens[0]
+		if token.Kind != STRING || token.Value != test.value || token.Raw != test.src {
+			t.Errorf("%s: got %s %q (raw %s), want STRING %q", test.src, TokenKindString(token.Kind), token.Value, token.Raw, test.value)
+		}
+
+		if len(errors) != test.errors {
+			t.Errorf("%s: got %d errors, want %d", test.src, len(errors), test.errors)
+		}
+
+		for _, err := range errors {
+			if err.Kind != InvalidEscape {
+				t.Errorf("%s: got error %v, want an invalid escape", test.src, err)
+			}
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		src, value string
+		err        bool
+	}{
+		{"42", "42", false},
+		{"0xFF", "0xFF", false},
+		{"0b101", "0b101", false},
+		{"0o17", "0o17", false},
+		{"1_000", "1000", false},
+		{"1.5e3", "1.5e3", false},
+		{"2E-2", "2E-2", false},
+		{"1__0", "", true},
+		{"1_", "", true},
+		{"0x", "", true},
+		{"1e", "", true},
+		{"0b102", "", true},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if test.err {
+			if len(errors) != 1 || errors[0].Kind != InvalidNumber || tokens[0].Kind != ILLEGAL {
+				t.Errorf("%s: got %s and errors %v, want one invalid number", test.src, TokenKindString(tokens[0].Kind), errors)
+			}
+
+			continue
+		}
+
+		if len(errors) > 0 || tokens[0].Kind != NUMBER || tokens[0].Value != test.value || tokens[0].Raw != test.src {
+			t.Errorf("%s: got %s %q (raw %s), errors %v; want NUMBER %q", test.src, TokenKindString(tokens[0].Kind), tokens[0].Value, tokens[0].Raw, errors, test.value)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	tokens, errors := Tokenize(`"éé" héllo_日本 x`)
+	if len(errors) > 0 {
+		t.Fatal(errors[0])
+	}
+
+	if got := kinds(tokens); !slices.Equal(got, []TokenKind{STRING, IDENTIFIER, IDENTIFIER}) {
+		t.Fatalf("got %v", got)
+	}
+
+	if tokens[1].Value != "héllo_日本" {
+		t.Errorf("got identifier %q", tokens[1].Value)
+	}
+
+	// Columns count runes while offsets count bytes
+	if got := tokens[2].Span.Start; got.Column != 15 || got.Offset != 21 {
+		t.Errorf("x starts at column %d, offset %d; want column 15, offset 21", got.Column, got.Offset)
+	}
+
+	tokens, errors = Tokenize("let a\xff = 1;")
+	if len(errors) != 1 || errors[0].Kind != InvalidUTF8 {
+		t.Fatalf("got errors %v, want one invalid UTF-8", errors)
+	}
+
+	if got := kinds(tokens); !slices.Equal(got, []TokenKind{LET, IDENTIFIER, ILLEGAL, ASSIGNMENT, NUMBER, SEMI_COLON}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestRangeTokens(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []TokenKind
+	}{
+		{"a..b", []TokenKind{IDENTIFIER, DOT_DOT, IDENTIFIER}},
+		{"0..=9 step 2", []TokenKind{NUMBER, DOT_DOT_EQUALS, NUMBER, IDENTIFIER, NUMBER}},
+		// The dots belong to the range, not to a fraction
+		{"1..2", []TokenKind{NUMBER, DOT_DOT, NUMBER}},
+		{"1.5..2", []TokenKind{NUMBER, DOT_DOT, NUMBER}},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if got := kinds(tokens); len(errors) > 0 || !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, errors %v; want %v", test.src, got, errors, test.want)
+		}
+	}
+}
+
+func TestQuestionTokens(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []TokenKind
+	}{
+		{"a ? b : c", []TokenKind{IDENTIFIER, QUESTION, IDENTIFIER, COLON, IDENTIFIER}},
+		{"a?b:c", []TokenKind{IDENTIFIER, QUESTION, IDENTIFIER, COLON, IDENTIFIER}},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if got := kinds(tokens); len(errors) > 0 || !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, errors %v; want %v", test.src, got, errors, test.want)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	src := "a // line  \nb /* block */ c"
+	tokens, _ := Tokenize(src)
+	if got := kinds(tokens); !slices.Equal(got, []TokenKind{IDENTIFIER, IDENTIFIER, IDENTIFIER}) {
+		t.Errorf("got %v, want comments skipped", got)
+	}
+
+	tokens, _ = TokenizeMode("", src, KeepComments)
+	if got := kinds(tokens); !slices.Equal(got, []TokenKind{IDENTIFIER, COMMENT, IDENTIFIER, COMMENT, IDENTIFIER}) {
+		t.Fatalf("got %v, want comments kept", got)
+	}
+
+	// Trailing blanks are not part of a line comment
+	if tokens[1].Value != "// line" || tokens[3].Value != "/* block */" {
+		t.Errorf("got comments %q and %q", tokens[1].Value, tokens[3].Value)
+	}
+}
+
+func TestBlockAndDocComments(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []TokenKind
+		errors int
+	}{
+		{"a /* b */ c", []TokenKind{IDENTIFIER, IDENTIFIER}, 0},
+		{"a /* b /* c */ d */ e", []TokenKind{IDENTIFIER, IDENTIFIER}, 0},
+		{"a /* b /* c */ d", []TokenKind{IDENTIFIER, ILLEGAL}, 1},
+		{"a */ b", []TokenKind{IDENTIFIER, STAR, SLASH, IDENTIFIER}, 0},
+		// Doc comments are kept even when other comments are not
+		{"/// doc\nfn", []TokenKind{DOC_COMMENT, FN}, 0},
+		{"//// rule\nfn", []TokenKind{FN}, 0},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if got := kinds(tokens); !slices.Equal(got, test.tokens) || len(errors) != test.errors {
+			t.Errorf("%q: got %v, errors %v; want %v, %d errors", test.src, got, errors, test.tokens, test.errors)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []TokenKind
+		errors int
+	}{
+		{"`plain`", []TokenKind{BACKTICK, TEMPLATE_STRING, BACKTICK}, 0},
+		{"`a${b}c`", []TokenKind{BACKTICK, TEMPLATE_STRING, DOLLAR_CURLY, IDENTIFIER, CLOSE_CURLY, TEMPLATE_STRING, BACKTICK}, 0},
+		// Braces inside the expression do not end it
+		{"`${P{x: 1}}`", []TokenKind{BACKTICK, TEMPLATE_STRING, DOLLAR_CURLY, IDENTIFIER, OPEN_CURLY, IDENTIFIER, COLON, NUMBER, CLOSE_CURLY, CLOSE_CURLY, TEMPLATE_STRING, BACKTICK}, 0},
+		{"`${`${a}`}`", []TokenKind{
+			BACKTICK, TEMPLATE_STRING, DOLLAR_CURLY,
+			BACKTICK, TEMPLATE_STRING, DOLLAR_CURLY, IDENTIFIER, CLOSE_CURLY, TEMPLATE_STRING, BACKTICK,
+			CLOSE_CURLY, TEMPLATE_STRING, BACKTICK,
+		}, 0},
+		{"`abc", []TokenKind{BACKTICK, ILLEGAL}, 1},
+	}
+
+	for _, test := range tests {
+		tokens, errors := Tokenize(test.src)
+		if got := kinds(tokens); !slices.Equal(got, test.tokens) || len(errors) != test.errors {
+			t.Errorf("%s: got %v, errors %v; want %v, %d errors", test.src, got, errors, test.tokens, test.errors)
+		}
+	}
+
+	tokens, _ := Tokenize("`\\` \\${x} \\n`")
+	if got := tokens[1]; got.Kind != TEMPLATE_STRING || got.Value != "` ${x} \n" {
+		t.Errorf("got %s %q, want the escapes replaced", TokenKindString(got.Kind), got.Value)
+	}
+}
@@ -0,0 +1,36 @@
+package lexer
+
+import "fmt"
+
+// Position is a single point in a source file. Line and Column are
+// 1-based, with Column counted in runes so that it matches what an editor
+// shows; Offset is the 0-based byte offset into the source.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+func (pos Position) String() string {
+	if pos.File == "" {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+// Span covers the half-open range [Start, End) of a source file.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// To returns a span starting where span starts and ending where other ends.
+func (span Span) To(other Span) Span {
+	return Span{Start: span.Start, End: other.End}
+}
+
+func (span Span) String() string {
+	return fmt.Sprintf("%s-%d:%d", span.Start, span.End.Line, span.End.Column)
+}
@@ -9,6 +9,9 @@ type TokenKind int
 
 const (
 	EOF TokenKind = iota
+	ILLEGAL
+	COMMENT     // only produced in the KeepComments mode
+	DOC_COMMENT // ///, always produced
 	NULL
 	TRUE
 	FALSE
@@ -16,6 +19,12 @@ const (
 	STRING
 	IDENTIFIER
 
+	// Template strings: BACKTICK TEMPLATE_STRING (DOLLAR_CURLY ... CLOSE_CURLY
+	// TEMPLATE_STRING)* BACKTICK
+	BACKTICK
+	TEMPLATE_STRING
+	DOLLAR_CURLY
+
 	// Grouping & Braces
 	OPEN_BRACKET
 	CLOSE_BRACKET
@@ -43,6 +52,7 @@ const (
 	// Symbols
 	DOT
 	DOT_DOT
+	DOT_DOT_EQUALS // ..=
 	SEMI_COLON
 	COLON
 	QUESTION
@@ -63,6 +73,14 @@ const (
 	STAR
 	PERCENT
 
+	// Bitwise
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
+	SHIFT_LEFT
+	SHIFT_RIGHT
+
 	// Reserved Keywords
 	LET
 	CONST
@@ -81,37 +99,48 @@ const (
 	IN
 	STRUCT
 	STATIC
+	RETURN
+	BREAK
+	CONTINUE
 
 	// Misc
 	NUM_TOKENS
 )
 
 var reserved_lu map[string]TokenKind = map[string]TokenKind{
-	"true":    TRUE,
-	"false":   FALSE,
-	"null":    NULL,
-	"let":     LET,
-	"const":   CONST,
-	"class":   CLASS,
-	"new":     NEW,
-	"import":  IMPORT,
-	"from":    FROM,
-	"fn":      FN,
-	"if":      IF,
-	"else":    ELSE,
-	"foreach": FOREACH,
-	"while":   WHILE,
-	"for":     FOR,
-	"export":  EXPORT,
-	"typeof":  TYPEOF,
-	"in":      IN,
-	"struct":  STRUCT,
-	"static":  STATIC,
+	"true":     TRUE,
+	"false":    FALSE,
+	"null":     NULL,
+	"let":      LET,
+	"const":    CONST,
+	"class":    CLASS,
+	"new":      NEW,
+	"import":   IMPORT,
+	"from":     FROM,
+	"fn":       FN,
+	"if":       IF,
+	"else":     ELSE,
+	"foreach":  FOREACH,
+	"while":    WHILE,
+	"for":      FOR,
+	"export":   EXPORT,
+	"typeof":   TYPEOF,
+	"in":       IN,
+	"struct":   STRUCT,
+	"static":   STATIC,
+	"return":   RETURN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
+// Token is a single lexeme. Value is what the token means, such as the
+// decoded content of a string, while Raw is the source text it was read
+// from.
 type Token struct {
 	Kind  TokenKind
 	Value string
+	Raw   string
+	Span  Span
 }
 
 func (token Token) IsOneOfMany(expectedTokens ...TokenKind) bool {
@@ -119,10 +148,10 @@ func (token Token) IsOneOfMany(expectedTokens ...TokenKind) bool {
 }
 
 func (token Token) Debug() {
-	if token.IsOneOfMany(IDENTIFIER, NUMBER, STRING) {
-		fmt.Printf("%s (%s)\n", TokenKindString(token.Kind), token.Value)
+	if token.IsOneOfMany(IDENTIFIER, NUMBER, STRING, TEMPLATE_STRING, ILLEGAL, COMMENT, DOC_COMMENT) {
+		fmt.Printf("%s %s (%s)\n", token.Span.Start, TokenKindString(token.Kind), token.Value)
 	} else {
-		fmt.Printf("%s()\n", TokenKindString(token.Kind))
+		fmt.Printf("%s %s()\n", token.Span.Start, TokenKindString(token.Kind))
 	}
 }
 
@@ -130,6 +159,12 @@ func TokenKindString(kind TokenKind) string {
 	switch kind {
 	case EOF:
 		return "eof"
+	case ILLEGAL:
+		return "illegal"
+	case COMMENT:
+		return "comment"
+	case DOC_COMMENT:
+		return "doc_comment"
 	case NULL:
 		return "null"
 	case NUMBER:
@@ -178,6 +213,8 @@ func TokenKindString(kind TokenKind) string {
 		return "dot"
 	case DOT_DOT:
 		return "dot_dot"
+	case DOT_DOT_EQUALS:
+		return "dot_dot_equals"
 	case SEMI_COLON:
 		return "semi_colon"
 	case COLON:
@@ -186,6 +223,12 @@ func TokenKindString(kind TokenKind) string {
 		return "question"
 	case COMMA:
 		return "comma"
+	case BACKTICK:
+		return "backtick"
+	case TEMPLATE_STRING:
+		return "template_string"
+	case DOLLAR_CURLY:
+		return "dollar_curly"
 	case PLUS_PLUS:
 		return "plus_plus"
 	case MINUS_MINUS:
@@ -208,6 +251,18 @@ func TokenKindString(kind TokenKind) string {
 		return "star"
 	case PERCENT:
 		return "percent"
+	case AMPERSAND:
+		return "ampersand"
+	case PIPE:
+		return "pipe"
+	case CARET:
+		return "caret"
+	case TILDE:
+		return "tilde"
+	case SHIFT_LEFT:
+		return "shift_left"
+	case SHIFT_RIGHT:
+		return "shift_right"
 	case LET:
 		return "let"
 	case CONST:
@@ -240,6 +295,12 @@ func TokenKindString(kind TokenKind) string {
 		return "struct"
 	case STATIC:
 		return "static"
+	case RETURN:
+		return "return"
+	case BREAK:
+		return "break"
+	case CONTINUE:
+		return "continue"
 	default:
 		return fmt.Sprintf("unknown(%d)", kind)
 	}
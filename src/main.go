@@ -1,18 +1,100 @@
 package main
 
 import (
-	"custom_parser/src/lexer"
-	"custom_parser/src/parser"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+)
 
-	"github.com/sanity-io/litter"
+// Exit codes
+const (
+	exitOK      = 0
+	exitErrors  = 1 // the input has syntax, type or runtime errors
+	exitFailure = 2 // bad usage, or a file could not be read or written
 )
 
+const usage = `usage: lang <command> [flags] [file ...]
+
+Commands:
+  tokens   print the tokens of each file
+  ast      print the syntax tree of each file
+  check    report syntax and type errors
+  run      execute each file
+  fmt      print each file in the canonical style
+
+With no files, or with "-", the source is read from standard input.
+Run "lang <command> -h" for the flags of a command.
+`
+
+type command struct {
+	name  string
+	flags func(fs *flag.FlagSet, cli *cli)
+	run   func(cli *cli, sources []source) int
+}
+
+var commands = []command{
+	{name: "tokens", flags: tokensFlags, run: tokensCommand},
+	{name: "ast", flags: astFlags, run: astCommand},
+	{name: "check", run: checkCommand},
+	{name: "run", flags: runFlags, run: runCommand},
+	{name: "fmt", flags: fmtFlags, run: fmtCommand},
+}
+
+// cli carries the streams and parsed flags shared by every command.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	showSpans bool // ast -spans
+	comments  bool // tokens -comments, ast -comments
+	typecheck bool // run -check
+	write     bool // fmt -w
+	diff      bool // fmt -d
+	list      bool // fmt -l
+}
+
 func main() {
-	bytes, _ := os.ReadFile("./examples/07.lang")
-	source := string(bytes)
+	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitFailure
+	}
+
+	if args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		if cmd.flags != nil {
+			cmd.flags(fs, c)
+		}
+
+		if err := fs.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+
+		sources, err := readSources(fs.Args(), stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+
+		return cmd.run(c, sources)
+	}
 
-	tokens := lexer.Tokenize(source)
-	ast := parser.Parse(tokens)
-	litter.Dump(ast)
+	fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+	return exitFailure
 }
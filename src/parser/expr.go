@@ -2,10 +2,10 @@ package parser
 
 import (
 	"custom_parser/src/ast"
-	"custom_parser/src/helpers"
+	"custom_parser/src/diagnostics"
 	"custom_parser/src/lexer"
-	"fmt"
 	"strconv"
+	"strings"
 )
 
 func parseExpr(p *parser, bp bindinPower) ast.Expr {
@@ -14,21 +14,21 @@ func parseExpr(p *parser, bp bindinPower) ast.Expr {
 	nudFn, exists := nudLu[tokenKind]
 
 	if !exists {
-		panic(fmt.Sprintf("NUD HANDLER EXPECTED FOR TOKEN %s\n", lexer.TokenKindString(tokenKind)))
+		p.errorAt(diagnostics.ExpectedExpression, p.currentToken().Span, "Expected an expression but found %s", lexer.TokenKindString(tokenKind))
 	}
 
 	// while we have a led and the current bp is less than bp of current token
 	// continue parsing the left hand side
 	left := nudFn(p)
-	for bpLu[p.currentTokenKind()] > bp {
+	for p.ledBindingPower() > bp {
 		tokenKind = p.currentTokenKind()
 		ledFn, exists := ledLu[tokenKind]
 
 		if !exists {
-			panic(fmt.Sprintf("LED HANDLER EXPECTED FOR TOKEN %s\n", lexer.TokenKindString(tokenKind)))
+			p.errorAt(diagnostics.UnexpectedOperator, p.currentToken().Span, "Unexpected %s following an expression", lexer.TokenKindString(tokenKind))
 		}
 
-		left = ledFn(p, left, bpLu[p.currentTokenKind()])
+		left = ledFn(p, left, p.ledBindingPower())
 	}
 
 	return left
@@ -41,6 +41,7 @@ func parsePrefixExpr(p *parser) ast.Expr {
 	return ast.PrefixExpr{
 		Operator:  operatorToken,
 		RightExpr: expr,
+		Loc:       p.spanFrom(operatorToken.Span),
 	}
 }
 
@@ -52,6 +53,7 @@ func parseAssignmentExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 		Operator: operatorToken,
 		Value:    rhs,
 		Assignee: left,
+		Loc:      p.spanFrom(left.Span()),
 	}
 }
 
@@ -63,35 +65,55 @@ func parseBinaryExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 		Left:     left,
 		Operator: operatorToken,
 		Right:    right,
+		Loc:      p.spanFrom(left.Span()),
 	}
 }
 
 func parsePrimaryExpr(p *parser) ast.Expr {
 	switch p.currentTokenKind() {
 	case lexer.NUMBER:
-		number, _ := strconv.ParseFloat(p.advance().Value, 64)
-		return ast.NumberExpr{
-			Value: number,
-		}
+		return parseNumberExpr(p, p.advance())
 	case lexer.STRING:
+		token := p.advance()
 		return ast.StringExpr{
-			Value: p.advance().Value,
+			Value: token.Value,
+			Raw:   token.Raw,
+			Loc:   token.Span,
+		}
+	case lexer.TRUE, lexer.FALSE:
+		token := p.advance()
+		return ast.BooleanExpr{
+			Value: token.Kind == lexer.TRUE,
+			Loc:   token.Span,
+		}
+	case lexer.NULL:
+		return ast.NullExpr{
+			Loc: p.advance().Span,
 		}
 	case lexer.IDENTIFIER:
+		token := p.advance()
 		return ast.SymbolExpr{
-			Value: p.advance().Value,
+			Value: token.Value,
+			Loc:   token.Span,
 		}
 	default:
-		panic(fmt.Sprintf("Cannot create primary expression from %s\n", lexer.TokenKindString(p.currentTokenKind())))
+		p.errorAt(diagnostics.ExpectedExpression, p.currentToken().Span, "Cannot create primary expression from %s", lexer.TokenKindString(p.currentTokenKind()))
+		return nil
 	}
 }
 
+// parseArrayLiteralExpr parses [a, b, c], handing over to
+// parseArrayInstantiationExpr when the brackets start a type as in []T{a, b}.
 func parseArrayLiteralExpr(p *parser) ast.Expr {
-	p.expect(lexer.OPEN_BRACKET)
+	if p.nextToken().Kind == lexer.CLOSE_BRACKET && p.peekToken(2).IsOneOfMany(lexer.IDENTIFIER, lexer.OPEN_BRACKET) {
+		return parseArrayInstantiationExpr(p)
+	}
+
+	start := p.expect(lexer.OPEN_BRACKET).Span
 	arrayContents := make([]ast.Expr, 0)
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_BRACKET {
-		arrayContents = append(arrayContents, parseExpr(p, logical))
+		arrayContents = append(arrayContents, parseNestedExpr(p, assignment))
 
 		if !p.currentToken().IsOneOfMany(lexer.EOF, lexer.CLOSE_BRACKET) {
 			p.expect(lexer.COMMA)
@@ -101,25 +123,38 @@ func parseArrayLiteralExpr(p *parser) ast.Expr {
 	p.expect(lexer.CLOSE_BRACKET)
 	return ast.ArrayLiteral{
 		Contents: arrayContents,
+		Loc:      p.spanFrom(start),
 	}
 }
 
+// parseGroupingExpr returns the parenthesised expression itself, with its
+// span widened to take in the parentheses.
 func parseGroupingExpr(p *parser) ast.Expr {
-	p.advance() //advance past grouping start
-	expr := parseExpr(p, default_bp)
+	start := p.advance().Span
+	expr := parseNestedExpr(p, default_bp)
 	p.expect(lexer.CLOSE_PAREN)
-	return expr
+	return expr.WithSpan(p.spanFrom(start))
 }
 
 func parseStructInstantiationExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
-	var structName = helpers.ExpectType[ast.SymbolExpr](left).Value
+	symbol, ok := left.(ast.SymbolExpr)
+	if !ok {
+		p.errorAt(diagnostics.InvalidInstantiation, left.Span(), "Expected a struct name before {")
+	}
+
+	var structName = symbol.Value
 	var properties = map[string]ast.Expr{}
 
 	p.expect(lexer.OPEN_CURLY)
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
-		propertyName := p.expect(lexer.IDENTIFIER).Value
+		propertyToken := p.expect(lexer.IDENTIFIER)
+		propertyName := propertyToken.Value
 		p.expect(lexer.COLON)
-		expr := parseExpr(p, logical)
+		expr := parseNestedExpr(p, assignment)
+
+		if _, exists := properties[propertyName]; exists {
+			p.reportAt(diagnostics.DuplicateProperty, p.spanFrom(propertyToken.Span), "Property %s has already been set in this instantiation", propertyName)
+		}
 
 		properties[propertyName] = expr
 		if p.currentTokenKind() != lexer.CLOSE_CURLY {
@@ -131,48 +166,92 @@ func parseStructInstantiationExpr(p *parser, left ast.Expr, bp bindinPower) ast.
 	return ast.StructInstantiationExpr{
 		StructName: structName,
 		Properties: properties,
+		Loc:        p.spanFrom(left.Span()),
 	}
 }
 
 func parseArrayInstantiationExpr(p *parser) ast.Expr {
-	p.expect(lexer.OPEN_BRACKET)
+	start := p.expect(lexer.OPEN_BRACKET).Span
+	p.expect(lexer.CLOSE_BRACKET)
+	underlying := parseType(p, default_bp)
 	contents := make([]ast.Expr, 0)
 
-	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_BRACKET {
-		contents = append(contents, parseExpr(p, logical))
-		if !p.currentToken().IsOneOfMany(lexer.EOF, lexer.CLOSE_BRACKET) {
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		contents = append(contents, parseNestedExpr(p, assignment))
+		if !p.currentToken().IsOneOfMany(lexer.EOF, lexer.CLOSE_CURLY) {
 			p.expect(lexer.COMMA)
 		}
 	}
 
-	p.expect(lexer.CLOSE_BRACKET)
-	return ast.ArrayLiteral{
-		Contents: contents,
+	p.expect(lexer.CLOSE_CURLY)
+	return ast.ArrayInstantiationExpr{
+		Underlying: underlying,
+		Contents:   contents,
+		Loc:        p.spanFrom(start),
 	}
 }
 
-func parseRangeExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
+// parseConditionalExpr parses cond ? a : b. Both branches are parsed at
+// assignment binding power, so a conditional in the alternate groups to the
+// right: a ? b : c ? d : e is a ? b : (c ? d : e).
+func parseConditionalExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 	p.advance()
+	consequent := parseNestedExpr(p, assignment)
+	p.expect(lexer.COLON)
+	alternate := parseExpr(p, assignment)
+
+	return ast.ConditionalExpr{
+		Condition:  left,
+		Consequent: consequent,
+		Alternate:  alternate,
+		Loc:        p.spanFrom(left.Span()),
+	}
+}
+
+// parseRangeExpr parses lower..upper and lower..=upper, optionally followed
+// by `step n`. step is only a keyword in this position. Ranges do not chain:
+// a..b..c is an error rather than a range of ranges.
+func parseRangeExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
+	inclusive := p.advance().Kind == lexer.DOT_DOT_EQUALS
+	upper := parseExpr(p, bp)
+
+	var step ast.Expr
+	if p.currentTokenKind() == lexer.IDENTIFIER && p.currentToken().Value == "step" {
+		p.advance()
+		step = parseExpr(p, bp)
+	}
+
+	if p.currentToken().IsOneOfMany(lexer.DOT_DOT, lexer.DOT_DOT_EQUALS) {
+		p.errorAt(diagnostics.UnexpectedOperator, p.currentToken().Span, "Ranges cannot be chained; use parentheses to group them")
+	}
+
 	return ast.RangeExpr{
-		Lower: left,
-		Upper: parseExpr(p, bp),
+		Lower:     left,
+		Upper:     upper,
+		Step:      step,
+		Inclusive: inclusive,
+		Loc:       p.spanFrom(left.Span()),
 	}
 }
 
 func parseMemberExpr(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 	isComputed := p.advance().Kind == lexer.OPEN_BRACKET
 	if isComputed {
-		rhs := parseExpr(p, bp)
+		rhs := parseNestedExpr(p, default_bp)
 		p.expect(lexer.CLOSE_BRACKET)
 		return ast.ComputedExpr{
 			Member:   left,
 			Property: rhs,
+			Loc:      p.spanFrom(left.Span()),
 		}
 	}
 
+	property := p.expect(lexer.IDENTIFIER).Value
 	return ast.MemberExpr{
 		Member:   left,
-		Property: p.expect(lexer.IDENTIFIER).Value,
+		Property: property,
+		Loc:      p.spanFrom(left.Span()),
 	}
 }
 
@@ -181,7 +260,7 @@ var parseCallExpr = func(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 	arguments := make([]ast.Expr, 0)
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
-		arguments = append(arguments, parseExpr(p, assignment))
+		arguments = append(arguments, parseNestedExpr(p, assignment))
 		if !p.currentToken().IsOneOfMany(lexer.EOF, lexer.CLOSE_PAREN) {
 			p.expect(lexer.COMMA)
 		}
@@ -191,16 +270,106 @@ var parseCallExpr = func(p *parser, left ast.Expr, bp bindinPower) ast.Expr {
 	return ast.CallExpr{
 		Method:    left,
 		Arguments: arguments,
+		Loc:       p.spanFrom(left.Span()),
 	}
 }
 
 var parseFnExpr = func(p *parser) ast.Expr {
-	p.expect(lexer.FN)
+	start := p.expect(lexer.FN).Span
 	functionParams, returnType, functionBody := parseFnParamsAndBody(p)
 
 	return ast.FunctionExpr{
 		Parameters: functionParams,
 		ReturnType: returnType,
 		Body:       functionBody,
+		Loc:        p.spanFrom(start),
 	}
 }
+
+func parseNewExpr(p *parser) ast.Expr {
+	start := p.advance().Span
+	classInstantiation := parseExpr(p, default_bp)
+
+	call, ok := classInstantiation.(ast.CallExpr)
+	if !ok {
+		p.errorAt(diagnostics.InvalidInstantiation, classInstantiation.Span(), "Expected a constructor call following new")
+	}
+
+	return ast.NewExpr{
+		Instantiation: call,
+		Loc:           p.spanFrom(start),
+	}
+}
+
+func parseTemplateExpr(p *parser) ast.Expr {
+	start := p.expect(lexer.BACKTICK).Span
+	parts := []ast.Expr{}
+	for {
+		text := p.expect(lexer.TEMPLATE_STRING)
+		parts = append(parts, ast.StringExpr{
+			Value: text.Value,
+			Raw:   text.Raw,
+			Loc:   text.Span,
+		})
+
+		if p.currentTokenKind() != lexer.DOLLAR_CURLY {
+			break
+		}
+
+		p.advance()
+		parts = append(parts, parseNestedExpr(p, default_bp))
+		p.expect(lexer.CLOSE_CURLY)
+	}
+
+	p.expect(lexer.BACKTICK)
+	return ast.TemplateExpr{
+		Parts: parts,
+		Loc:   p.spanFrom(start),
+	}
+}
+
+// parseNumberExpr converts a literal the lexer has already validated.
+// Integers must fit in 64 bits; a prefix such as 0x selects their base.
+func parseNumberExpr(p *parser, token lexer.Token) ast.Expr {
+	literal := token.Value
+	base := 10
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+
+	expr := ast.NumberExpr{
+		Integral: base != 10 || !strings.ContainsAny(literal, ".eE"),
+		Raw:      token.Raw,
+		Loc:      token.Span,
+	}
+
+	if !expr.Integral {
+		value, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			p.reportAt(diagnostics.NumberOutOfRange, token.Span, "Number %s is out of range", token.Raw)
+		}
+
+		expr.Value = value
+		return expr
+	}
+
+	if base != 10 {
+		literal = literal[2:]
+	}
+
+	value, err := strconv.ParseInt(literal, base, 64)
+	if err != nil {
+		p.reportAt(diagnostics.NumberOutOfRange, token.Span, "Integer %s does not fit in 64 bits", token.Raw)
+	}
+
+	expr.Int = value
+	expr.Value = float64(value)
+	return expr
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestGroupingSpan(t *testing.T) {
+	// The expression of each statement covers all of it but the semicolon
+	for _, src := range []string{"(a + b) * c;", "a * (b + c);", "((a));", "(f)(x);"} {
+		program, codes := parse(src)
+		if len(codes) > 0 {
+			t.Fatalf("%s: %v", src, codes)
+		}
+
+		stmt := program.Body[0].(ast.ExpressionStmt)
+		span := stmt.Expression.Span()
+		if got, want := src[span.Start.Offset:span.End.Offset], src[:len(src)-1]; got != want {
+			t.Errorf("expression spans %q, want %q", got, want)
+		}
+
+		if got := src[stmt.Loc.Start.Offset:stmt.Loc.End.Offset]; got != src {
+			t.Errorf("statement spans %q, want %q", got, src)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		src      string
+		integral bool
+		int      int64
+		value    float64
+	}{
+		{"0xFF;", true, 255, 255},
+		{"0b101;", true, 5, 5},
+		{"1_000;", true, 1000, 1000},
+		{"9223372036854775807;", true, 9223372036854775807, 9223372036854775807},
+		{"1.5e3;", false, 0, 1500},
+		{"2E-2;", false, 0, 0.02},
+	}
+
+	for _, test := range tests {
+		program, codes := parse(test.src)
+		if len(codes) > 0 {
+			t.Fatalf("%s: %v", test.src, codes)
+		}
+
+		number := program.Body[0].(ast.ExpressionStmt).Expression.(ast.NumberExpr)
+		if number.Integral != test.integral || number.Int != test.int || number.Value != test.value {
+			t.Errorf("%s: got %+v, want integral %v, int %d, value %v", test.src, number, test.integral, test.int, test.value)
+		}
+	}
+
+	for _, src := range []string{"9223372036854775808;", "0xFFFFFFFFFFFFFFFFF;", "1e400;"} {
+		if _, codes := parse(src); !slices.Equal(codes, []diagnostics.Code{diagnostics.NumberOutOfRange}) {
+			t.Errorf("%s: got %v, want a number out of range", src, codes)
+		}
+	}
+}
+
+func TestTemplateParts(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string // text, or the type of each interpolated expression
+	}{
+		{"`plain`;", []string{"plain"}},
+		{"``;", []string{""}},
+		{"`a${b + 1}c${d}`;", []string{"a", "ast.BinaryExpr", "c", "ast.SymbolExpr", ""}},
+		{"`${P{x: 1}.x}`;", []string{"", "ast.MemberExpr", ""}},
+		{"`<${`${a}`}>`;", []string{"<", "ast.TemplateExpr", ">"}},
+	}
+
+	for _, test := range tests {
+		program, codes := parse(test.src)
+		if len(codes) > 0 {
+			t.Fatalf("%s: %v", test.src, codes)
+		}
+
+		template := program.Body[0].(ast.ExpressionStmt).Expression.(ast.TemplateExpr)
+		var got []string
+		for _, part := range template.Parts {
+			if text, ok := part.(ast.StringExpr); ok {
+				got = append(got, text.Value)
+			} else {
+				got = append(got, fmt.Sprintf("%T", part))
+			}
+		}
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.want)
+		}
+	}
+
+	if _, codes := parse("`a${}b`;"); len(codes) == 0 {
+		t.Error("an empty interpolation parsed")
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	program, codes := parse("true; false; null; !true;")
+	if len(codes) > 0 {
+		t.Fatal(codes)
+	}
+
+	var got []string
+	for _, stmt := range program.Body {
+		got = append(got, fmt.Sprintf("%T", stmt.(ast.ExpressionStmt).Expression))
+	}
+
+	want := []string{"ast.BooleanExpr", "ast.BooleanExpr", "ast.NullExpr", "ast.PrefixExpr"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if value := program.Body[1].(ast.ExpressionStmt).Expression.(ast.BooleanExpr).Value; value {
+		t.Error("false parsed as true")
+	}
+}
+
+func TestRanges(t *testing.T) {
+	program, codes := parse("0..n - 1 step 2; 1..=3;")
+	if len(codes) > 0 {
+		t.Fatal(codes)
+	}
+
+	r := program.Body[0].(ast.ExpressionStmt).Expression.(ast.RangeExpr)
+	if _, ok := r.Upper.(ast.BinaryExpr); !ok || r.Inclusive || r.Step == nil {
+		t.Errorf("got upper %T, inclusive %v, step %v; want n - 1 exclusive, step 2", r.Upper, r.Inclusive, r.Step)
+	}
+
+	if r := program.Body[1].(ast.ExpressionStmt).Expression.(ast.RangeExpr); !r.Inclusive || r.Step != nil {
+		t.Errorf("got inclusive %v, step %v", r.Inclusive, r.Step)
+	}
+
+	// Ranges bind tighter than && but looser than comparisons
+	program, _ = parse("a && 0..1 == b;")
+	logical := program.Body[0].(ast.ExpressionStmt).Expression.(ast.BinaryExpr)
+	if _, ok := logical.Right.(ast.RangeExpr); !ok {
+		t.Errorf("got %T on the right of &&, want a range", logical.Right)
+	}
+
+	if _, codes := parse("0..1..2;"); !slices.Equal(codes, []diagnostics.Code{diagnostics.UnexpectedOperator}) {
+		t.Errorf("chained ranges: got %v", codes)
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	program, codes := parse("x = a ? b : c ? d : e;")
+	if len(codes) > 0 {
+		t.Fatal(codes)
+	}
+
+	// Assignment binds looser, and the conditional groups to the right
+	assignment := program.Body[0].(ast.ExpressionStmt).Expression.(ast.AssignmentExpr)
+	outer := assignment.Value.(ast.ConditionalExpr)
+	if _, ok := outer.Alternate.(ast.ConditionalExpr); !ok {
+		t.Errorf("got alternate %T, want the nested conditional", outer.Alternate)
+	}
+
+	if _, codes := parse("a ? b;"); len(codes) == 0 {
+		t.Error("a conditional without an alternate parsed")
+	}
+}
@@ -11,7 +11,9 @@ const (
 	default_bp bindinPower = iota
 	comma
 	assignment
+	conditional
 	logical
+	ranges
 	relational
 	additive
 	multiplicative
@@ -21,6 +23,22 @@ const (
 	primary
 )
 
+// Binding powers exported for tools, such as the formatter, that need to
+// know how expressions group without parsing them.
+const (
+	AssignmentPrecedence  = int(assignment)
+	ConditionalPrecedence = int(conditional)
+	RangePrecedence       = int(ranges)
+	UnaryPrecedence       = int(unary)
+	PrimaryPrecedence     = int(primary)
+)
+
+// InfixPrecedence is the binding power of kind as an infix operator, or 0
+// when kind is not one.
+func InfixPrecedence(kind lexer.TokenKind) int {
+	return int(bpLu[kind])
+}
+
 type (
 	stmtHandler func(p *parser) ast.Stmt
 	nudHandler  func(p *parser) ast.Expr
@@ -44,13 +62,13 @@ func led(kind lexer.TokenKind, bp bindinPower, ledFn ledHandler) {
 	ledLu[kind] = ledFn
 }
 
+// nud leaves bpLu alone: a token such as DASH can be both a prefix and an
+// infix operator, and only its led binding power matters to parseExpr.
 func nud(kind lexer.TokenKind, nudFn nudHandler) {
-	bpLu[kind] = primary
 	nudLu[kind] = nudFn
 }
 
 func stmt(kind lexer.TokenKind, stmtFn stmtHandler) {
-	bpLu[kind] = default_bp
 	stmtLu[kind] = stmtFn
 }
 
@@ -59,10 +77,15 @@ func createTokenLookups() {
 	led(lexer.PLUS_EQUALS, assignment, parseAssignmentExpr)
 	led(lexer.MINUS_EQUALS, assignment, parseAssignmentExpr)
 
+	led(lexer.QUESTION, conditional, parseConditionalExpr)
+
 	// Logical
 	led(lexer.AND, logical, parseBinaryExpr)
 	led(lexer.OR, logical, parseBinaryExpr)
-	led(lexer.DOT_DOT, logical, parseBinaryExpr)
+
+	// Ranges
+	led(lexer.DOT_DOT, ranges, parseRangeExpr)
+	led(lexer.DOT_DOT_EQUALS, ranges, parseRangeExpr)
 
 	// Relational
 	led(lexer.LESS, relational, parseBinaryExpr)
@@ -79,54 +102,39 @@ func createTokenLookups() {
 	led(lexer.STAR, multiplicative, parseBinaryExpr)
 	led(lexer.PERCENT, multiplicative, parseBinaryExpr)
 
+	// Bitwise, grouped with the arithmetic operators they resemble
+	led(lexer.PIPE, additive, parseBinaryExpr)
+	led(lexer.CARET, additive, parseBinaryExpr)
+	led(lexer.AMPERSAND, multiplicative, parseBinaryExpr)
+	led(lexer.SHIFT_LEFT, multiplicative, parseBinaryExpr)
+	led(lexer.SHIFT_RIGHT, multiplicative, parseBinaryExpr)
+
 	// Literals & Symbols
 	nud(lexer.NUMBER, parsePrimaryExpr)
 	nud(lexer.STRING, parsePrimaryExpr)
+	nud(lexer.BACKTICK, parseTemplateExpr)
 	nud(lexer.IDENTIFIER, parsePrimaryExpr)
+	nud(lexer.TRUE, parsePrimaryExpr)
+	nud(lexer.FALSE, parsePrimaryExpr)
+	nud(lexer.NULL, parsePrimaryExpr)
 
 	//Unary/Prefix
-	nud(lexer.TYPEOF, parsePrimaryExpr)
-	nud(lexer.DASH, parsePrefixExpr)
-	nud(lexer.NOT, parsePrimaryExpr)
-	nud(lexer.OPEN_BRACKET, parsePrimaryExpr)
-
-	// Member / Computed // Call
-	led(lexer.DOT, member, parseMemberExpr)
-	led(lexer.OPEN_BRACKET, member, parseMemberExpr)
-	led(lexer.OPEN_PAREN, member, parseMemberExpr)
-
-	// Grouping Expr
-	nud(lexer.OPEN_PAREN, parseGroupingExpr)
-	nud(lexer.FN, parseGroupingExpr)
-	nud(lexer.NEW, func(p *parser) ast.Expr {
-		p.advance()
-		classInstantiation := parseExpr(p, default_bp)
-
-		return ast.NewExpr{
-			Instantiation: ast.ExpectExpr[ast.CallExpr](classInstantiation),
-		}
-	})
-
 	nud(lexer.TYPEOF, parsePrefixExpr)
 	nud(lexer.DASH, parsePrefixExpr)
 	nud(lexer.NOT, parsePrefixExpr)
-	nud(lexer.OPEN_BRACKET, parseArrayInstantiationExpr)
+	nud(lexer.TILDE, parsePrefixExpr)
+	nud(lexer.OPEN_BRACKET, parseArrayLiteralExpr)
 
 	// Call/Member/Arrays expressions
 	led(lexer.DOT, member, parseMemberExpr)
 	led(lexer.OPEN_BRACKET, member, parseMemberExpr)
 	led(lexer.OPEN_PAREN, call, parseCallExpr)
+	led(lexer.OPEN_CURLY, call, parseStructInstantiationExpr)
 
 	// Grouping Expr
 	nud(lexer.OPEN_PAREN, parseGroupingExpr)
 	nud(lexer.FN, parseFnExpr)
-	nud(lexer.NEW, func(p *parser) ast.Expr {
-		p.advance()
-		classInstation := parseExpr(p, default_bp)
-		return ast.NewExpr{
-			Instantiation: ast.ExpectExpr[ast.CallExpr](classInstation),
-		}
-	})
+	nud(lexer.NEW, parseNewExpr)
 
 	// Statements
 	stmt(lexer.OPEN_CURLY, parseBlockStmt)
@@ -138,4 +146,9 @@ func createTokenLookups() {
 	stmt(lexer.FOREACH, parseForEarchStmt)
 	stmt(lexer.CLASS, parseClassDeclStmt)
 	stmt(lexer.STRUCT, parseStructDeclStmt)
+	stmt(lexer.RETURN, parseReturnStmt)
+	stmt(lexer.WHILE, parseWhileStmt)
+	stmt(lexer.FOR, parseForStmt)
+	stmt(lexer.BREAK, parseBreakStmt)
+	stmt(lexer.CONTINUE, parseBreakStmt)
 }
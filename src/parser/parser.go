@@ -2,24 +2,69 @@ package parser
 
 import (
 	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
 	"custom_parser/src/lexer"
 )
 
 type parser struct {
-	tokens []lexer.Token
-	pos    int
+	tokens      []lexer.Token
+	pos         int
+	diagnostics []diagnostics.Diagnostic
+
+	// comments are the COMMENT tokens not yet attached to a node, in
+	// source order. They are kept out of tokens so the grammar never sees
+	// them.
+	comments []ast.Comment
+
+	// noStructLiterals is set while parsing the header of a statement that
+	// is followed by a block, so that the { in `if x {` opens the block
+	// instead of instantiating a struct named x.
+	noStructLiterals bool
+
+	// inFunction is set while parsing a function body, where return is
+	// allowed.
+	inFunction bool
+
+	// loops holds the labels of the loops around the statement being
+	// parsed, innermost last, with "" for a loop without a label. label is
+	// a label that has been read and waits for the loop it names.
+	loops []string
+	label string
 }
 
-func createParser(tokens []lexer.Token) *parser {
+func init() {
 	createTokenLookups()
 	createTokenTypeLookups()
+}
+
+func createParser(tokens []lexer.Token) *parser {
+	// ILLEGAL tokens have already been reported by the lexer
+	valid := make([]lexer.Token, 0, len(tokens))
+	comments := []ast.Comment{}
+	for _, token := range tokens {
+		switch token.Kind {
+		case lexer.ILLEGAL:
+		case lexer.COMMENT, lexer.DOC_COMMENT:
+			comments = append(comments, ast.Comment{Text: token.Value, Loc: token.Span})
+		default:
+			valid = append(valid, token)
+		}
+	}
 
 	return &parser{
-		tokens: tokens,
+		tokens:   valid,
+		comments: comments,
 	}
 }
 
-func Parse(tokens []lexer.Token) ast.BlockStmt {
+// Parse builds the program from tokens. Statements containing syntax errors
+// are replaced by ast.BadStmt and parsing continues, so every error in the
+// file is returned as a diagnostic alongside the partial program.
+//
+// Comments, present when the tokens were produced in the KeepComments
+// mode, are attached to the statement they precede or trail; those after
+// the last statement trail the program itself.
+func Parse(tokens []lexer.Token) (ast.BlockStmt, []diagnostics.Diagnostic) {
 	p := createParser(tokens)
 	body := make([]ast.Stmt, 0)
 
@@ -28,6 +73,8 @@ func Parse(tokens []lexer.Token) ast.BlockStmt {
 	}
 
 	return ast.BlockStmt{
-		Body: body,
-	}
+		Comments: ast.Comments{Trailing: p.leadingComments()},
+		Body:     body,
+		Loc:      tokens[0].Span.To(tokens[len(tokens)-1].Span),
+	}, p.diagnostics
 }
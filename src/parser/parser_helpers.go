@@ -1,17 +1,26 @@
 package parser
 
 import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
 	"custom_parser/src/lexer"
 	"fmt"
+	"slices"
 )
 
+// bailout is raised by fail to unwind the parser once a diagnostic has been
+// recorded. It never escapes the package.
+type bailout struct{}
+
 func (p *parser) currentToken() lexer.Token {
 	return p.tokens[p.pos]
 }
 
 func (p *parser) advance() lexer.Token {
 	tk := p.currentToken()
-	p.pos++
+	if tk.Kind != lexer.EOF {
+		p.pos++
+	}
 	return tk
 }
 
@@ -20,7 +29,12 @@ func (p *parser) hasTokens() bool {
 }
 
 func (p *parser) nextToken() lexer.Token {
-	return p.tokens[p.pos+1]
+	return p.peekToken(1)
+}
+
+// peekToken looks n tokens ahead, stopping at the trailing EOF.
+func (p *parser) peekToken(n int) lexer.Token {
+	return p.tokens[min(p.pos+n, len(p.tokens)-1)]
 }
 
 func (p *parser) previousToken() lexer.Token {
@@ -31,21 +45,209 @@ func (p *parser) currentTokenKind() lexer.TokenKind {
 	return p.tokens[p.pos].Kind
 }
 
-func (p *parser) expectError(expectedKind lexer.TokenKind, err any) lexer.Token {
+func (p *parser) expectError(expectedKind lexer.TokenKind, message string) lexer.Token {
 	token := p.currentToken()
 	kind := token.Kind
 
 	if kind != expectedKind {
-		if err == nil {
-			err = fmt.Sprintf("Expected %s but recieved %s instead\n", lexer.TokenKindString(expectedKind), lexer.TokenKindString(kind))
+		if message == "" {
+			message = fmt.Sprintf("Expected %s but received %s instead", lexer.TokenKindString(expectedKind), lexer.TokenKindString(kind))
 		}
 
-		panic(err)
+		p.fail(diagnostics.Diagnostic{
+			Code:     diagnostics.UnexpectedToken,
+			Severity: diagnostics.Error,
+			Message:  message,
+			Span:     token.Span,
+			Expected: []lexer.TokenKind{expectedKind},
+			Found:    kind,
+		})
 	}
 
 	return p.advance()
 }
 
 func (p *parser) expect(expectedKind lexer.TokenKind) lexer.Token {
-	return p.expectError(expectedKind, nil)
+	return p.expectError(expectedKind, "")
+}
+
+// report records a diagnostic without interrupting the parse.
+func (p *parser) report(diagnostic diagnostics.Diagnostic) {
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// fail records a diagnostic and abandons the construct being parsed.
+func (p *parser) fail(diagnostic diagnostics.Diagnostic) {
+	p.report(diagnostic)
+	panic(bailout{})
+}
+
+// errorAt fails with an error diagnostic covering span.
+func (p *parser) errorAt(code diagnostics.Code, span lexer.Span, format string, args ...any) {
+	p.reportAt(code, span, format, args...)
+	panic(bailout{})
+}
+
+// reportAt records an error diagnostic covering span but lets the parse
+// carry on, for mistakes that leave the syntax tree intact.
+func (p *parser) reportAt(code diagnostics.Code, span lexer.Span, format string, args ...any) {
+	p.report(diagnostics.Diagnostic{
+		Code:     code,
+		Severity: diagnostics.Error,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+		Found:    p.currentTokenKind(),
+	})
+}
+
+// synchronize discards tokens after a syntax error up to the next likely
+// statement boundary: past a semicolon, or before a closing curly or a
+// statement keyword. It returns a BadStmt covering everything from start.
+func (p *parser) synchronize(start int) ast.Stmt {
+	// Always make progress, otherwise the caller would fail on the same
+	// token forever.
+	if p.pos == start {
+		p.advance()
+	}
+
+	for p.hasTokens() {
+		kind := p.currentTokenKind()
+		if kind == lexer.SEMI_COLON {
+			p.advance()
+			break
+		}
+
+		if _, isStmt := stmtLu[kind]; kind == lexer.CLOSE_CURLY || (isStmt && kind != lexer.OPEN_CURLY) {
+			break
+		}
+
+		p.advance()
+	}
+
+	return ast.BadStmt{
+		Loc: p.spanFrom(p.tokens[start].Span),
+	}
+}
+
+// parseMember runs parse over one member of a class or struct body. When
+// parse gives up on a syntax error, the rest of the member is skipped so
+// that the body carries on with the next one.
+func (p *parser) parseMember(parse func(), memberStarts ...lexer.TokenKind) {
+	start := p.pos
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+
+			p.synchronizeMember(start, memberStarts)
+		}
+	}()
+
+	parse()
+}
+
+// synchronizeMember discards tokens after a syntax error in a member up to
+// the start of the next one: past a semicolon or a method body, or before
+// one of memberStarts or the curly closing the declaration.
+func (p *parser) synchronizeMember(start int, memberStarts []lexer.TokenKind) {
+	// Always make progress, otherwise the member would fail on the same
+	// keyword forever.
+	if p.pos == start && slices.Contains(memberStarts, p.currentTokenKind()) {
+		p.advance()
+	}
+
+	depth := 0
+	for p.hasTokens() {
+		switch kind := p.currentTokenKind(); {
+		case kind == lexer.OPEN_CURLY:
+			depth++
+		case kind == lexer.CLOSE_CURLY:
+			if depth == 0 {
+				return
+			}
+
+			depth--
+			if depth == 0 {
+				p.advance()
+				return
+			}
+		case depth == 0 && kind == lexer.SEMI_COLON:
+			p.advance()
+			return
+		case depth == 0 && slices.Contains(memberStarts, kind):
+			return
+		}
+
+		p.advance()
+	}
+}
+
+// spanFrom returns the span running from start up to the end of the last
+// consumed token.
+func (p *parser) spanFrom(start lexer.Span) lexer.Span {
+	return start.To(p.previousToken().Span)
+}
+
+// ledBindingPower is the binding power of the current token when used as
+// an infix operator.
+func (p *parser) ledBindingPower() bindinPower {
+	kind := p.currentTokenKind()
+	if kind == lexer.OPEN_CURLY && p.noStructLiterals {
+		return default_bp
+	}
+
+	return bpLu[kind]
+}
+
+// parseHeaderExpr parses the expression between a keyword and the block
+// that follows it, e.g. the condition of an if statement.
+func parseHeaderExpr(p *parser, bp bindinPower) ast.Expr {
+	previous := p.noStructLiterals
+	p.noStructLiterals = true
+	defer func() { p.noStructLiterals = previous }()
+
+	return parseExpr(p, bp)
+}
+
+// parseNestedExpr parses an expression enclosed by brackets of some kind,
+// where struct literals are unambiguous again.
+func parseNestedExpr(p *parser, bp bindinPower) ast.Expr {
+	previous := p.noStructLiterals
+	p.noStructLiterals = false
+	defer func() { p.noStructLiterals = previous }()
+
+	return parseExpr(p, bp)
+}
+
+// leadingComments takes the unattached comments before the current token.
+func (p *parser) leadingComments() []ast.Comment {
+	next := p.currentToken().Span.Start.Offset
+	return p.takeComments(func(comment lexer.Position) bool {
+		return comment.Offset < next
+	})
+}
+
+// trailingComments takes, for a node ending at end, the unattached comments
+// inside it and the one following it on the same line.
+func (p *parser) trailingComments(end lexer.Position) []ast.Comment {
+	next := p.currentToken().Span.Start.Offset
+	return p.takeComments(func(comment lexer.Position) bool {
+		return comment.Offset < next && (comment.Offset < end.Offset || comment.Line == end.Line)
+	})
+}
+
+func (p *parser) takeComments(take func(comment lexer.Position) bool) []ast.Comment {
+	n := 0
+	for n < len(p.comments) && take(p.comments[n].Loc.Start) {
+		n++
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	taken := p.comments[:n:n]
+	p.comments = p.comments[n:]
+	return taken
 }
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/lexer"
+	"slices"
+	"testing"
+)
+
+// parse returns the program parsed from src and the codes of its
+// diagnostics, in order.
+func parse(src string) (ast.BlockStmt, []diagnostics.Code) {
+	tokens, _ := lexer.Tokenize(src)
+	program, list := Parse(tokens)
+	var codes []diagnostics.Code
+	for _, d := range list {
+		codes = append(codes, d.Code)
+	}
+
+	return program, codes
+}
+
+// texts lists the text of each comment.
+func texts(comments []ast.Comment) []string {
+	list := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		list = append(list, comment.Text)
+	}
+
+	return list
+}
+
+func TestCommentAttachment(t *testing.T) {
+	src := `// header
+let a = 1; // one
+/* inner */ let b = f(1, /* arg */ 2);
+class C {
+  // field
+  let x: int; // x
+  fn f() {} // f
+}
+// end
+`
+	tokens, _ := lexer.TokenizeMode("", src, lexer.KeepComments)
+	program, list := Parse(tokens)
+	if len(list) > 0 {
+		t.Fatal(list[0])
+	}
+
+	class := program.Body[2].(ast.ClassDeclarationStmt)
+	tests := []struct {
+		node string
+		got  ast.Comments
+		want [2][]string // leading and trailing
+	}{
+		{"let a", program.Body[0].Attached(), [2][]string{{"// header"}, {"// one"}}},
+		{"let b", program.Body[1].Attached(), [2][]string{{"/* inner */"}, {"/* arg */"}}},
+		{"class C", class.Comments, [2][]string{{}, {}}},
+		{"field x", class.Fields[0].Comments, [2][]string{{"// field"}, {"// x"}}},
+		{"method f", class.Methods[0].Comments, [2][]string{{}, {"// f"}}},
+		{"program", program.Comments, [2][]string{{}, {"// end"}}},
+	}
+
+	for _, test := range tests {
+		leading, trailing := texts(test.got.Leading), texts(test.got.Trailing)
+		if !slices.Equal(leading, test.want[0]) || !slices.Equal(trailing, test.want[1]) {
+			t.Errorf("%s: got leading %q, trailing %q; want %q, %q", test.node, leading, trailing, test.want[0], test.want[1])
+		}
+	}
+}
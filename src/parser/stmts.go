@@ -2,11 +2,35 @@ package parser
 
 import (
 	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
 	"custom_parser/src/lexer"
-	"fmt"
+	"slices"
+	"strings"
 )
 
-func parseStmt(p *parser) ast.Stmt {
+func parseStmt(p *parser) (stmt ast.Stmt) {
+	start := p.pos
+	leading := p.leadingComments()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+
+			stmt = p.synchronize(start)
+		}
+
+		stmt = stmt.WithComments(ast.Comments{
+			Leading:  leading,
+			Trailing: p.trailingComments(stmt.Span().End),
+		})
+		stmt = withDoc(stmt, docText(leading, stmt.Span().Start))
+	}()
+
+	if p.currentTokenKind() == lexer.IDENTIFIER && p.nextToken().Kind == lexer.COLON {
+		return parseLabeledStmt(p)
+	}
+
 	stmtFn, exists := stmtLu[p.currentTokenKind()]
 	if exists {
 		return stmtFn(p)
@@ -17,6 +41,46 @@ func parseStmt(p *parser) ast.Stmt {
 
 	return ast.ExpressionStmt{
 		Expression: expression,
+		Loc:        p.spanFrom(expression.Span()),
+	}
+}
+
+// docText joins the /// comments on the lines directly above start, with
+// their markers removed.
+func docText(leading []ast.Comment, start lexer.Position) string {
+	first := len(leading)
+	line := start.Line
+	for first > 0 && leading[first-1].IsDoc() && leading[first-1].Loc.Start.Line == line-1 {
+		first--
+		line--
+	}
+
+	lines := make([]string, 0, len(leading)-first)
+	for _, comment := range leading[first:] {
+		text := strings.TrimPrefix(comment.Text, "///")
+		lines = append(lines, strings.TrimPrefix(text, " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// withDoc sets the Doc of the declarations that have one.
+func withDoc(stmt ast.Stmt, doc string) ast.Stmt {
+	switch decl := stmt.(type) {
+	case ast.VarDeclStmt:
+		decl.Doc = doc
+		return decl
+	case ast.FunctionDeclStmt:
+		decl.Doc = doc
+		return decl
+	case ast.ClassDeclarationStmt:
+		decl.Doc = doc
+		return decl
+	case ast.StructDeclStmt:
+		decl.Doc = doc
+		return decl
+	default:
+		return stmt
 	}
 }
 
@@ -25,6 +89,7 @@ func parseExpressionStmt(p *parser) ast.ExpressionStmt {
 	p.expect(lexer.SEMI_COLON)
 	return ast.ExpressionStmt{
 		Expression: expression,
+		Loc:        p.spanFrom(expression.Span()),
 	}
 }
 
@@ -32,6 +97,7 @@ func parseVarDeclStmt(p *parser) ast.Stmt {
 	var explicitType ast.Type
 	var assinedValue ast.Expr
 
+	start := p.currentToken().Span
 	isConstant := p.advance().Kind == lexer.CONST
 	varName := p.expectError(lexer.IDENTIFIER, "Inside variable declaration expected to find variable name").Value
 
@@ -44,13 +110,13 @@ func parseVarDeclStmt(p *parser) ast.Stmt {
 		p.expect(lexer.ASSIGNMENT)
 		assinedValue = parseExpr(p, assignment)
 	} else if explicitType == nil {
-		panic("Missing either right-hand side in var declaration or explit type.")
+		p.errorAt(diagnostics.MissingInitializer, p.spanFrom(start), "Missing either right-hand side in var declaration or explicit type")
 	}
 
 	p.expect(lexer.SEMI_COLON)
 
 	if isConstant && assinedValue == nil {
-		panic("Cannot define constant without providing a value!")
+		p.errorAt(diagnostics.ConstWithoutValue, p.spanFrom(start), "Cannot define constant without providing a value")
 	}
 
 	return ast.VarDeclStmt{
@@ -58,12 +124,20 @@ func parseVarDeclStmt(p *parser) ast.Stmt {
 		VariableName:  varName,
 		AssignedValue: assinedValue,
 		ExplicitType:  explicitType,
+		Loc:           p.spanFrom(start),
 	}
 }
 
 func parseBlockStmt(p *parser) ast.Stmt {
-	p.expect(lexer.OPEN_CURLY)
+	start := p.expect(lexer.OPEN_CURLY).Span
 	body := []ast.Stmt{}
+
+	// A block inside a header, e.g. a function expression in a condition,
+	// may use struct literals again.
+	previous := p.noStructLiterals
+	p.noStructLiterals = false
+	defer func() { p.noStructLiterals = previous }()
+
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
 		body = append(body, parseStmt(p))
 	}
@@ -71,22 +145,104 @@ func parseBlockStmt(p *parser) ast.Stmt {
 	p.expect(lexer.CLOSE_CURLY)
 	return ast.BlockStmt{
 		Body: body,
+		Loc:  p.spanFrom(start),
 	}
 }
 
+// constructorName is the method a class runs when instantiated with new.
+const constructorName = "mount"
+
 func parseClassDeclStmt(p *parser) ast.Stmt {
-	p.advance()
-	className := p.expect(lexer.IDENTIFIER).Value
-	classBody := parseBlockStmt(p)
+	start := p.advance().Span
+	class := ast.ClassDeclarationStmt{
+		Name: p.expect(lexer.IDENTIFIER).Value,
+	}
 
-	return ast.ClassDeclarationStmt{
-		Name: className,
-		Body: ast.ExpectStmt[ast.BlockStmt](classBody).Body,
+	members := map[string]bool{}
+	p.expect(lexer.OPEN_CURLY)
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
+		p.parseMember(func() { parseClassMember(p, &class, members) }, lexer.LET, lexer.CONST, lexer.FN, lexer.STATIC)
+	}
+
+	p.expect(lexer.CLOSE_CURLY)
+	class.Loc = p.spanFrom(start)
+	return class
+}
+
+// parseClassMember parses one field or method into class. members holds
+// the names declared so far.
+func parseClassMember(p *parser, class *ast.ClassDeclarationStmt, members map[string]bool) {
+	leading := p.leadingComments()
+	memberStart := p.currentToken().Span
+	isStatic := false
+	if p.currentTokenKind() == lexer.STATIC {
+		isStatic = true
+		p.advance()
+	}
+
+	var name string
+	switch p.currentTokenKind() {
+	case lexer.LET, lexer.CONST:
+		field := parseClassField(p, memberStart, isStatic)
+		field.Comments = ast.Comments{Leading: leading, Trailing: p.trailingComments(field.Loc.End)}
+		name = field.Name
+		class.Fields = append(class.Fields, field)
+	case lexer.FN:
+		method := parseClassMethod(p, memberStart, isStatic)
+		method.Comments = ast.Comments{Leading: leading, Trailing: p.trailingComments(method.Loc.End)}
+		name = method.Name
+		if name != constructorName {
+			class.Methods = append(class.Methods, method)
+		} else if isStatic {
+			p.reportAt(diagnostics.InvalidClassMember, method.Loc, "The %s constructor cannot be static", constructorName)
+		} else {
+			class.Constructor = &method
+		}
+	default:
+		// Parse the offending statement so the class body can carry on
+		// from whatever follows it.
+		stmt := parseStmt(p)
+		p.reportAt(diagnostics.InvalidClassMember, stmt.Span(), "Only fields and methods are allowed inside a class body")
+		return
+	}
+
+	if members[name] {
+		p.reportAt(diagnostics.DuplicateProperty, p.spanFrom(memberStart), "Member %s has already been defined inside class %s", name, class.Name)
+	}
+
+	members[name] = true
+}
+
+func parseClassField(p *parser, start lexer.Span, isStatic bool) ast.ClassField {
+	decl := ast.ExpectStmt[ast.VarDeclStmt](parseVarDeclStmt(p))
+
+	return ast.ClassField{
+		Name:         decl.VariableName,
+		IsStatic:     isStatic,
+		IsConstant:   decl.IsConstant,
+		Type:         decl.ExplicitType,
+		DefaultValue: decl.AssignedValue,
+		Loc:          p.spanFrom(start),
+	}
+}
+
+func parseClassMethod(p *parser, start lexer.Span, isStatic bool) ast.ClassMethod {
+	p.expect(lexer.FN)
+	name := p.expect(lexer.IDENTIFIER).Value
+	params, returnType, body := parseFnParamsAndBody(p)
+
+	return ast.ClassMethod{
+		Name:       name,
+		IsStatic:   isStatic,
+		Parameters: params,
+		ReturnType: returnType,
+		Body:       body,
+		Loc:        p.spanFrom(start),
 	}
 }
 
 func parseFnDeclStmt(p *parser) ast.Stmt {
-	p.advance()
+	start := p.advance().Span
 	fnName := p.expect(lexer.IDENTIFIER).Value
 	functionParameters, returnType, fnBody := parseFnParamsAndBody(p)
 
@@ -95,66 +251,105 @@ func parseFnDeclStmt(p *parser) ast.Stmt {
 		ReturnType: returnType,
 		Body:       fnBody,
 		Name:       fnName,
+		Loc:        p.spanFrom(start),
 	}
 }
 
 func parseStructDeclStmt(p *parser) ast.Stmt {
-	p.expect(lexer.STRUCT)
+	start := p.expect(lexer.STRUCT).Span
 	var properties = map[string]ast.StructProperty{}
 	var methods = map[string]ast.StructMethod{}
 	var structName = p.expect(lexer.IDENTIFIER).Value
 
 	p.expect(lexer.OPEN_CURLY)
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY {
-		var isStatic bool
-		var propertyName string
+		p.parseMember(func() { parseStructMember(p, properties, methods) }, lexer.FN, lexer.STATIC)
+	}
 
-		if p.currentTokenKind() == lexer.STATIC {
-			isStatic = true
-			p.expect(lexer.STATIC)
-		}
+	p.expect(lexer.CLOSE_CURLY)
+	return ast.StructDeclStmt{
+		StructName: structName,
+		Properties: properties,
+		Methods:    methods,
+		Loc:        p.spanFrom(start),
+	}
+}
 
-		if p.currentTokenKind() == lexer.IDENTIFIER {
-			propertyName = p.expect(lexer.IDENTIFIER).Value
-			p.expectError(lexer.COLON, "Expected to find colon following property name inside struct declaration")
-			structType := parseType(p, default_bp)
-			p.expect(lexer.SEMI_COLON)
+// parseStructMember parses one property or method into properties or
+// methods.
+func parseStructMember(p *parser, properties map[string]ast.StructProperty, methods map[string]ast.StructMethod) {
+	var isStatic bool
+	var propertyName string
+	leading := p.leadingComments()
+	propertyStart := p.currentToken().Span
+
+	if p.currentTokenKind() == lexer.STATIC {
+		isStatic = true
+		p.expect(lexer.STATIC)
+	}
 
-			_, exists := properties[propertyName]
-			if exists {
-				panic(fmt.Sprintf("Property %s has already been defined inside struct declaration", propertyName))
-			}
+	if p.currentTokenKind() == lexer.IDENTIFIER {
+		propertyName = p.expect(lexer.IDENTIFIER).Value
+		p.expectError(lexer.COLON, "Expected to find colon following property name inside struct declaration")
+		structType := parseType(p, default_bp)
+		p.expect(lexer.SEMI_COLON)
 
-			properties[propertyName] = ast.StructProperty{
-				IsStatic: isStatic,
-				Type:     structType,
-			}
+		_, isProperty := properties[propertyName]
+		_, isMethod := methods[propertyName]
+		if isProperty || isMethod {
+			p.reportAt(diagnostics.DuplicateProperty, p.spanFrom(propertyStart), "Property %s has already been defined inside struct declaration", propertyName)
+		}
 
-			continue
+		loc := p.spanFrom(propertyStart)
+		properties[propertyName] = ast.StructProperty{
+			Comments: ast.Comments{Leading: leading, Trailing: p.trailingComments(loc.End)},
+			IsStatic: isStatic,
+			Type:     structType,
+			Loc:      loc,
 		}
 
-		panic("cannot currently handle methods inside struct declaration")
+		return
 	}
 
-	p.expect(lexer.CLOSE_CURLY)
-	return ast.StructDeclStmt{
-		StructName: structName,
-		Properties: properties,
-		Methods:    methods,
+	if p.currentTokenKind() == lexer.FN {
+		p.advance()
+		methodName := p.expect(lexer.IDENTIFIER).Value
+		params, returnType, body := parseFnParamsAndBody(p)
+
+		_, isProperty := properties[methodName]
+		_, isMethod := methods[methodName]
+		if isProperty || isMethod {
+			p.reportAt(diagnostics.DuplicateProperty, p.spanFrom(propertyStart), "Method %s has already been defined inside struct declaration", methodName)
+		}
+
+		loc := p.spanFrom(propertyStart)
+		methods[methodName] = ast.StructMethod{
+			Comments:   ast.Comments{Leading: leading, Trailing: p.trailingComments(loc.End)},
+			IsStatic:   isStatic,
+			Parameters: params,
+			ReturnType: returnType,
+			Body:       body,
+			Loc:        loc,
+		}
+
+		return
 	}
+
+	p.errorAt(diagnostics.InvalidStructMember, p.currentToken().Span, "Expected a property or method inside struct declaration but found %s", lexer.TokenKindString(p.currentTokenKind()))
 }
 
 func parseFnParamsAndBody(p *parser) ([]ast.Parameter, ast.Type, []ast.Stmt) {
 	functionParams := make([]ast.Parameter, 0)
 	p.expect(lexer.OPEN_PAREN)
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN {
-		paramName := p.expect(lexer.IDENTIFIER).Value
+		paramToken := p.expect(lexer.IDENTIFIER)
 		p.expect(lexer.COLON)
 		paramType := parseType(p, default_bp)
 
 		functionParams = append(functionParams, ast.Parameter{
-			Name: paramName,
+			Name: paramToken.Value,
 			Type: paramType,
+			Loc:  p.spanFrom(paramToken.Span),
 		})
 
 		if !p.currentToken().IsOneOfMany(lexer.CLOSE_PAREN, lexer.EOF) {
@@ -169,14 +364,20 @@ func parseFnParamsAndBody(p *parser) ([]ast.Parameter, ast.Type, []ast.Stmt) {
 		returnType = parseType(p, default_bp)
 	}
 
+	// Loops around a function expression cannot be broken out of from
+	// inside it.
+	inFunction, loops := p.inFunction, p.loops
+	p.inFunction, p.loops = true, nil
+	defer func() { p.inFunction, p.loops = inFunction, loops }()
+
 	functionBody := ast.ExpectStmt[ast.BlockStmt](parseBlockStmt(p)).Body
 
 	return functionParams, returnType, functionBody
 }
 
 func parseIfStmt(p *parser) ast.Stmt {
-	p.advance()
-	condition := parseExpr(p, assignment)
+	start := p.advance().Span
+	condition := parseHeaderExpr(p, assignment)
 	consequent := parseBlockStmt(p)
 
 	var alternate ast.Stmt
@@ -193,11 +394,31 @@ func parseIfStmt(p *parser) ast.Stmt {
 		Condition:  condition,
 		Consequent: consequent,
 		Alternate:  alternate,
+		Loc:        p.spanFrom(start),
+	}
+}
+
+func parseReturnStmt(p *parser) ast.Stmt {
+	start := p.advance().Span
+
+	var value ast.Expr
+	if p.currentTokenKind() != lexer.SEMI_COLON {
+		value = parseExpr(p, default_bp)
+	}
+
+	p.expect(lexer.SEMI_COLON)
+	if !p.inFunction {
+		p.reportAt(diagnostics.InvalidReturn, p.spanFrom(start), "Cannot return outside of a function")
+	}
+
+	return ast.ReturnStmt{
+		Value: value,
+		Loc:   p.spanFrom(start),
 	}
 }
 
 func parseImportStmt(p *parser) ast.Stmt {
-	p.advance()
+	start := p.advance().Span
 	var importFrom string
 	importName := p.expect(lexer.IDENTIFIER).Value
 
@@ -210,30 +431,157 @@ func parseImportStmt(p *parser) ast.Stmt {
 
 	p.expect(lexer.SEMI_COLON)
 	return ast.ImportStmt{
-		Name: importFrom,
+		Name: importName,
 		From: importFrom,
+		Loc:  p.spanFrom(start),
+	}
+}
+
+// parseLabeledStmt parses `name: loop`, leaving the label for the loop's
+// own handler to pick up.
+func parseLabeledStmt(p *parser) ast.Stmt {
+	start := p.advance()
+	p.expect(lexer.COLON)
+	if !p.currentToken().IsOneOfMany(lexer.WHILE, lexer.FOR, lexer.FOREACH) {
+		p.errorAt(diagnostics.InvalidLabel, p.spanFrom(start.Span), "Only loops can be labelled but %s labels %s", start.Value, lexer.TokenKindString(p.currentTokenKind()))
+	}
+
+	if slices.Contains(p.loops, start.Value) {
+		p.reportAt(diagnostics.InvalidLabel, start.Span, "Label %s is already used by an enclosing loop", start.Value)
+	}
+
+	p.label = start.Value
+	loop := stmtLu[p.currentTokenKind()](p)
+	switch loop := loop.(type) {
+	case ast.WhileStmt:
+		loop.Loc = p.spanFrom(start.Span)
+		return loop
+	case ast.ForStmt:
+		loop.Loc = p.spanFrom(start.Span)
+		return loop
+	case ast.ForeachStmt:
+		loop.Loc = p.spanFrom(start.Span)
+		return loop
+	default:
+		return loop
 	}
 }
 
+// takeLabel returns the label waiting for the loop being parsed, if any.
+func (p *parser) takeLabel() string {
+	label := p.label
+	p.label = ""
+	return label
+}
+
+// parseLoopBody parses the block of a loop, inside which break and continue
+// refer to it.
+func parseLoopBody(p *parser, label string) []ast.Stmt {
+	p.loops = append(p.loops, label)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+
+	return ast.ExpectStmt[ast.BlockStmt](parseBlockStmt(p)).Body
+}
+
+func parseWhileStmt(p *parser) ast.Stmt {
+	label := p.takeLabel()
+	start := p.advance().Span
+	condition := parseHeaderExpr(p, assignment)
+	body := parseLoopBody(p, label)
+
+	return ast.WhileStmt{
+		Label:     label,
+		Condition: condition,
+		Body:      body,
+		Loc:       p.spanFrom(start),
+	}
+}
+
+// parseForStmt parses `for init; condition; post { }`, where each of the
+// three clauses may be left out.
+func parseForStmt(p *parser) ast.Stmt {
+	label := p.takeLabel()
+	start := p.advance().Span
+
+	var init ast.Stmt
+	switch p.currentTokenKind() {
+	case lexer.LET, lexer.CONST:
+		init = parseVarDeclStmt(p)
+	case lexer.SEMI_COLON:
+		p.advance()
+	default:
+		init = parseExpressionStmt(p)
+	}
+
+	var condition, post ast.Expr
+	if p.currentTokenKind() != lexer.SEMI_COLON {
+		condition = parseHeaderExpr(p, assignment)
+	}
+
+	p.expect(lexer.SEMI_COLON)
+	if p.currentTokenKind() != lexer.OPEN_CURLY {
+		post = parseHeaderExpr(p, default_bp)
+	}
+
+	body := parseLoopBody(p, label)
+	return ast.ForStmt{
+		Label:     label,
+		Init:      init,
+		Condition: condition,
+		Post:      post,
+		Body:      body,
+		Loc:       p.spanFrom(start),
+	}
+}
+
+// parseBreakStmt parses both break and continue, with an optional label
+// naming the loop they apply to.
+func parseBreakStmt(p *parser) ast.Stmt {
+	keyword := p.advance()
+	label := ""
+	if p.currentTokenKind() == lexer.IDENTIFIER {
+		label = p.advance().Value
+	}
+
+	p.expect(lexer.SEMI_COLON)
+	loc := p.spanFrom(keyword.Span)
+	switch {
+	case len(p.loops) == 0:
+		p.reportAt(diagnostics.InvalidBreak, loc, "Cannot %s outside of a loop", keyword.Value)
+	case label != "" && !slices.Contains(p.loops, label):
+		p.reportAt(diagnostics.InvalidBreak, loc, "Cannot %s %s: no enclosing loop has that label", keyword.Value, label)
+	}
+
+	if keyword.Kind == lexer.CONTINUE {
+		return ast.ContinueStmt{Label: label, Loc: loc}
+	}
+
+	return ast.BreakStmt{Label: label, Loc: loc}
+}
+
 func parseForEarchStmt(p *parser) ast.Stmt {
-	p.advance()
-	valueName := p.expect(lexer.IDENTIFIER).Value
+	label := p.takeLabel()
+	start := p.advance().Span
+	value := p.expect(lexer.IDENTIFIER)
 
-	var index bool
+	var index lexer.Token
 	if p.currentTokenKind() == lexer.COMMA {
 		p.expect(lexer.COMMA)
-		p.expect(lexer.IDENTIFIER)
-		index = true
+		index = p.expect(lexer.IDENTIFIER)
 	}
 
 	p.expect(lexer.IN)
-	iterable := parseExpr(p, default_bp)
-	body := ast.ExpectStmt[ast.BlockStmt](parseBlockStmt(p)).Body
+	iterable := parseHeaderExpr(p, default_bp)
+	body := parseLoopBody(p, label)
 
 	return ast.ForeachStmt{
-		Value:    valueName,
-		Index:    index,
+		Label:    label,
+		Value:    value.Value,
+		ValueLoc: value.Span,
+		Index:    index.Value,
+		IndexLoc: index.Span,
 		Iterable: iterable,
 		Body:     body,
+		Loc:      p.spanFrom(start),
 	}
 }
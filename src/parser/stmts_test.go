@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// outline names each statement of body by its type, listing the members
+// of classes and structs, e.g. "class C {y f()}".
+func outline(body []ast.Stmt) []string {
+	names := make([]string, len(body))
+	for i, stmt := range body {
+		switch decl := stmt.(type) {
+		case ast.ClassDeclarationStmt:
+			var members []string
+			for _, field := range decl.Fields {
+				members = append(members, field.Name)
+			}
+
+			for _, method := range decl.Methods {
+				members = append(members, method.Name+"()")
+			}
+
+			names[i] = fmt.Sprintf("class %s {%s}", decl.Name, strings.Join(members, " "))
+		case ast.StructDeclStmt:
+			members := slices.Sorted(maps.Keys(decl.Properties))
+			for _, name := range slices.Sorted(maps.Keys(decl.Methods)) {
+				members = append(members, name+"()")
+			}
+
+			names[i] = fmt.Sprintf("struct %s {%s}", decl.StructName, strings.Join(members, " "))
+		default:
+			names[i] = strings.TrimPrefix(fmt.Sprintf("%T", stmt), "ast.")
+		}
+	}
+
+	return names
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		src     string
+		codes   []diagnostics.Code
+		outline []string
+	}{
+		{"let a = ;\nlet b = 1;", []diagnostics.Code{diagnostics.ExpectedExpression}, []string{"BadStmt", "VarDeclStmt"}},
+		{"let a = 1\nlet b = 2;", []diagnostics.Code{diagnostics.UnexpectedToken}, []string{"BadStmt", "VarDeclStmt"}},
+		{"fn f() { let a = ; return 1; }\nlet b = 2;", []diagnostics.Code{diagnostics.ExpectedExpression}, []string{"FunctionDeclStmt", "VarDeclStmt"}},
+		{"return 1;", []diagnostics.Code{diagnostics.InvalidReturn}, []string{"ReturnStmt"}},
+		{"break;", []diagnostics.Code{diagnostics.InvalidBreak}, []string{"BreakStmt"}},
+
+		// A bad member is skipped without losing the rest of the class
+		{"class C { let x: = 1; let y = 2; fn f() {} }\nlet z = 3;", []diagnostics.Code{diagnostics.ExpectedType}, []string{"class C {y f()}", "VarDeclStmt"}},
+		{"class C { fn f(a: ) { let y = 1; } fn g() {} }", []diagnostics.Code{diagnostics.ExpectedType}, []string{"class C {g()}"}},
+		{"class C { static let = 1; fn g() {} }", []diagnostics.Code{diagnostics.UnexpectedToken}, []string{"class C {g()}"}},
+
+		// and likewise for a struct
+		{"struct P { let x = 1; y: int; }\nlet z = 3;", []diagnostics.Code{diagnostics.InvalidStructMember}, []string{"struct P {y}", "VarDeclStmt"}},
+		{"struct P { x int; y: int; }", []diagnostics.Code{diagnostics.UnexpectedToken}, []string{"struct P {y}"}},
+		{"struct P { x: ; static y: int; }", []diagnostics.Code{diagnostics.ExpectedType}, []string{"struct P {y}"}},
+		{"struct P { fn f(a: ) { let y = 1; } x: int; fn g() {} }", []diagnostics.Code{diagnostics.ExpectedType}, []string{"struct P {x g()}"}},
+	}
+
+	for _, test := range tests {
+		program, codes := parse(test.src)
+		if !slices.Equal(codes, test.codes) {
+			t.Errorf("%s\ngot codes  %v\nwant codes %v", test.src, codes, test.codes)
+		}
+
+		if got := outline(program.Body); !slices.Equal(got, test.outline) {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, got, test.outline)
+		}
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"/// Adds.\nfn add() {}", "Adds."},
+		{"/// One\n///   two\nclass C {}", "One\n  two"},
+		{"///Tight\nstruct P { x: int; }", "Tight"},
+		{"/// The answer.\nconst answer = 42;", "The answer."},
+		// Only the doc comments directly above count
+		{"/// Stale.\n\nfn f() {}", ""},
+		{"/// Stale.\n// note\nfn f() {}", ""},
+		{"/// Stale.\n//// rule\nfn f() {}", ""},
+		{"// note\n/// Kept.\nfn f() {}", "Kept."},
+	}
+
+	for _, test := range tests {
+		program, codes := parse(test.src)
+		if len(codes) > 0 {
+			t.Fatalf("%s: %v", test.src, codes)
+		}
+
+		var doc string
+		switch decl := program.Body[0].(type) {
+		case ast.FunctionDeclStmt:
+			doc = decl.Doc
+		case ast.ClassDeclarationStmt:
+			doc = decl.Doc
+		case ast.StructDeclStmt:
+			doc = decl.Doc
+		case ast.VarDeclStmt:
+			doc = decl.Doc
+		}
+
+		if doc != test.want {
+			t.Errorf("%s\ngot  %q\nwant %q", test.src, doc, test.want)
+		}
+	}
+}
+
+func TestForeachIndex(t *testing.T) {
+	src := "foreach value, index in xs {}"
+	program, codes := parse(src)
+	if len(codes) > 0 {
+		t.Fatal(codes)
+	}
+
+	stmt := program.Body[0].(ast.ForeachStmt)
+	if stmt.Value != "value" || stmt.Index != "index" {
+		t.Errorf("got value %q, index %q", stmt.Value, stmt.Index)
+	}
+
+	if got := src[stmt.IndexLoc.Start.Offset:stmt.IndexLoc.End.Offset]; got != "index" {
+		t.Errorf("index spans %q", got)
+	}
+
+	program, _ = parse("foreach value in xs {}")
+	if stmt := program.Body[0].(ast.ForeachStmt); stmt.Index != "" {
+		t.Errorf("got index %q without one written", stmt.Index)
+	}
+}
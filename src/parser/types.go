@@ -2,8 +2,8 @@ package parser
 
 import (
 	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
 	"custom_parser/src/lexer"
-	"fmt"
 )
 
 type (
@@ -37,19 +37,22 @@ func createTokenTypeLookups() {
 }
 
 func parseSymbolType(p *parser) ast.Type {
+	token := p.expect(lexer.IDENTIFIER)
 	return ast.SymbolType{
-		Name: p.expect(lexer.IDENTIFIER).Value,
+		Name: token.Value,
+		Loc:  token.Span,
 	}
 }
 
 func parseArrayType(p *parser) ast.Type {
-	p.advance()
+	start := p.advance().Span
 	p.expect(lexer.CLOSE_BRACKET)
 
 	var underlyingType = parseType(p, default_bp)
 
 	return ast.ArrayType{
 		Underlying: underlyingType,
+		Loc:        p.spanFrom(start),
 	}
 }
 
@@ -59,7 +62,7 @@ func parseType(p *parser, bp bindinPower) ast.Type {
 	nudFn, exists := type_nudLu[tokenKind]
 
 	if !exists {
-		panic(fmt.Sprintf("TYPE_NUD HANDLER EXPECTED FOR TOKEN %s\n", lexer.TokenKindString(tokenKind)))
+		p.errorAt(diagnostics.ExpectedType, p.currentToken().Span, "Expected a type but found %s", lexer.TokenKindString(tokenKind))
 	}
 
 	// while we have a led and the current bp is less than bp of current token
@@ -70,7 +73,7 @@ func parseType(p *parser, bp bindinPower) ast.Type {
 		ledFn, exists := type_ledLu[tokenKind]
 
 		if !exists {
-			panic(fmt.Sprintf("LED HANDLER EXPECTED FOR TOKEN %s\n", lexer.TokenKindString(tokenKind)))
+			p.errorAt(diagnostics.UnexpectedOperator, p.currentToken().Span, "Unexpected %s following a type", lexer.TokenKindString(tokenKind))
 		}
 
 		left = ledFn(p, left, type_bpLu[p.currentTokenKind()])
@@ -0,0 +1,507 @@
+package typecheck
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/lexer"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type checker struct {
+	diagnostics []diagnostics.Diagnostic
+
+	// result is the return type of the function being checked, or nil
+	// outside of any function.
+	result Type
+
+	// hoisted holds the type each struct and class declaration introduces,
+	// by the declaration's span. A declaration whose name was taken still
+	// gets a type of its own, so that its members can be checked.
+	hoisted map[lexer.Span]Type
+}
+
+// Check validates the type annotations in program and the expressions they
+// constrain. It never stops at the first problem; every mismatch found is
+// returned as a diagnostic.
+func Check(program ast.BlockStmt) []diagnostics.Diagnostic {
+	c := &checker{hoisted: map[lexer.Span]Type{}}
+	checkBody(c, program.Body, newGlobalScope())
+
+	// Hoisting resolves declarations ahead of the statements around them
+	slices.SortStableFunc(c.diagnostics, func(a, b diagnostics.Diagnostic) int {
+		return a.Span.Start.Offset - b.Span.Start.Offset
+	})
+
+	return c.diagnostics
+}
+
+func newGlobalScope() *scope {
+	global := newScope(nil)
+	for _, t := range []PrimitiveType{Int, Float, Number, String, Boolean, Void, Any} {
+		global.types[t.Name] = t
+	}
+
+	global.symbols["println"] = symbol{Type: FunctionType{Parameters: []Type{Any}, Variadic: true, Return: Void}, Constant: true}
+	global.symbols["print"] = symbol{Type: FunctionType{Parameters: []Type{Any}, Variadic: true, Return: Void}, Constant: true}
+	global.symbols["len"] = symbol{Type: FunctionType{Parameters: []Type{Any}, Return: Int}, Constant: true}
+	return global
+}
+
+func (c *checker) errorAt(code diagnostics.Code, span lexer.Span, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diagnostics.Diagnostic{
+		Code:     code,
+		Severity: diagnostics.Error,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+	})
+}
+
+func (c *checker) declare(s *scope, name string, t Type, constant bool, span lexer.Span) {
+	if _, exists := s.symbols[name]; exists {
+		c.errorAt(diagnostics.Redeclared, span, "%s has already been declared in this scope", name)
+		return
+	}
+
+	s.symbols[name] = symbol{Type: t, Constant: constant}
+}
+
+// declareType names t in s, unless s already has a type or a value called
+// name, reporting whether it did.
+func (c *checker) declareType(s *scope, name string, t Type, span lexer.Span) bool {
+	_, isType := s.types[name]
+	_, isValue := s.symbols[name]
+	if isType || isValue {
+		c.errorAt(diagnostics.Redeclared, span, "%s has already been declared in this scope", name)
+		return false
+	}
+
+	s.types[name] = t
+	return true
+}
+
+// expectAssignable reports a mismatch when from cannot be used as to.
+func (c *checker) expectAssignable(from, to Type, span lexer.Span, context string) {
+	if !assignable(from, to) {
+		c.errorAt(diagnostics.TypeMismatch, span, "Cannot use %s as %s in %s", from, to, context)
+	}
+}
+
+// resolveType converts a parsed annotation into a checker type. Unknown
+// names are reported and treated as Any so checking can continue.
+func resolveType(c *checker, t ast.Type, s *scope) Type {
+	switch t := t.(type) {
+	case nil:
+		return Any
+	case ast.SymbolType:
+		resolved, exists := s.lookupType(t.Name)
+		if !exists {
+			c.errorAt(diagnostics.UnknownType, t.Loc, "Unknown type %s", t.Name)
+			return Any
+		}
+
+		return resolved
+	case ast.ArrayType:
+		return ArrayType{Element: resolveType(c, t.Underlying, s)}
+	}
+
+	c.errorAt(diagnostics.UnknownType, t.Span(), "Unsupported type annotation %T", t)
+	return Any
+}
+
+// checkBody checks a list of statements sharing one scope. Type and
+// function declarations are hoisted so they can be used before the point
+// they are declared. The result is the type of the final statement when it
+// is an expression statement, and nil otherwise.
+func checkBody(c *checker, body []ast.Stmt, s *scope) Type {
+	for _, stmt := range body {
+		if decl, ok := stmt.(ast.StructDeclStmt); ok {
+			structType := &StructType{Name: decl.StructName, Fields: map[string]Type{}, Methods: map[string]Type{}, Static: map[string]Type{}}
+			c.hoisted[decl.Loc] = structType
+			if c.declareType(s, decl.StructName, structType, decl.Loc) {
+				c.declare(s, decl.StructName, StructDefinition{Struct: structType}, true, decl.Loc)
+			}
+		}
+
+		if decl, ok := stmt.(ast.ClassDeclarationStmt); ok {
+			class := &ClassType{Name: decl.Name, Members: map[string]Type{}, Static: map[string]Type{}}
+			c.hoisted[decl.Loc] = class
+			if c.declareType(s, decl.Name, InstanceType{Class: class}, decl.Loc) {
+				c.declare(s, decl.Name, class, true, decl.Loc)
+			}
+		}
+	}
+
+	for _, stmt := range body {
+		switch decl := stmt.(type) {
+		case ast.StructDeclStmt:
+			if structType, ok := c.hoisted[decl.Loc].(*StructType); ok {
+				resolveStructMembers(c, decl, structType, s)
+			}
+		case ast.ClassDeclarationStmt:
+			if class, ok := c.hoisted[decl.Loc].(*ClassType); ok {
+				resolveClassMembers(c, decl, class, s)
+			}
+		case ast.FunctionDeclStmt:
+			c.declare(s, decl.Name, functionType(c, decl.Parameters, decl.ReturnType, s), true, decl.Loc)
+		}
+	}
+
+	var result Type
+	for _, stmt := range body {
+		result = checkStmt(c, stmt, s)
+	}
+
+	return result
+}
+
+func functionType(c *checker, params []ast.Parameter, returnType ast.Type, s *scope) FunctionType {
+	fnType := FunctionType{Return: Any}
+	for _, param := range params {
+		fnType.Parameters = append(fnType.Parameters, resolveType(c, param.Type, s))
+	}
+
+	if returnType != nil {
+		fnType.Return = resolveType(c, returnType, s)
+	}
+
+	return fnType
+}
+
+func checkStmt(c *checker, stmt ast.Stmt, s *scope) Type {
+	switch stmt := stmt.(type) {
+	case ast.ExpressionStmt:
+		return checkExpr(c, stmt.Expression, s)
+	case ast.BlockStmt:
+		checkBody(c, stmt.Body, newScope(s))
+	case ast.VarDeclStmt:
+		checkVarDeclStmt(c, stmt, s)
+	case ast.FunctionDeclStmt:
+		fnType, _ := s.symbols[stmt.Name].Type.(FunctionType)
+		checkFunctionBody(c, stmt.Parameters, fnType, stmt.Body, stmt.Loc, s)
+	case ast.IfStmt:
+		condition := checkExpr(c, stmt.Condition, s)
+		c.expectAssignable(condition, Boolean, stmt.Condition.Span(), "if condition")
+		checkStmt(c, stmt.Consequent, s)
+		if stmt.Alternate != nil {
+			checkStmt(c, stmt.Alternate, s)
+		}
+	case ast.ReturnStmt:
+		checkReturnStmt(c, stmt, s)
+	case ast.WhileStmt:
+		condition := checkExpr(c, stmt.Condition, s)
+		c.expectAssignable(condition, Boolean, stmt.Condition.Span(), "while condition")
+		checkBody(c, stmt.Body, newScope(s))
+	case ast.ForStmt:
+		checkForStmt(c, stmt, s)
+	case ast.ForeachStmt:
+		body := newScope(s)
+		value, index := elementType(c, checkExpr(c, stmt.Iterable, s), stmt.Iterable.Span())
+		c.declare(body, stmt.Value, value, false, stmt.ValueLoc)
+		if stmt.Index != "" {
+			c.declare(body, stmt.Index, index, false, stmt.IndexLoc)
+		}
+
+		checkBody(c, stmt.Body, body)
+	case ast.ImportStmt:
+		c.declare(s, stmt.Name, Any, true, stmt.Loc)
+	case ast.ClassDeclarationStmt:
+		checkClassDeclStmt(c, stmt, s)
+	case ast.StructDeclStmt:
+		checkStructDeclStmt(c, stmt, s)
+	case ast.BadStmt:
+		// Already reported by the parser
+	}
+
+	return nil
+}
+
+func checkVarDeclStmt(c *checker, stmt ast.VarDeclStmt, s *scope) {
+	var declared Type
+	if stmt.ExplicitType != nil {
+		declared = resolveType(c, stmt.ExplicitType, s)
+	}
+
+	if stmt.AssignedValue != nil {
+		value := checkExpr(c, stmt.AssignedValue, s)
+		if declared == nil {
+			declared = inferred(value)
+		} else {
+			c.expectAssignable(value, declared, stmt.AssignedValue.Span(), fmt.Sprintf("declaration of %s", stmt.VariableName))
+		}
+	}
+
+	c.declare(s, stmt.VariableName, declared, stmt.IsConstant, stmt.Loc)
+}
+
+// checkFunctionBody checks body with the parameters in scope. A function
+// returns the value of a return statement or, when it runs off the end of
+// its body, the value of a final expression statement. When a return type
+// other than void is declared, every path through the body must end in one
+// of the two, and the values must match the type.
+func checkFunctionBody(c *checker, params []ast.Parameter, fnType FunctionType, body []ast.Stmt, span lexer.Span, s *scope) {
+	fnScope := newScope(s)
+	for i, param := range params {
+		paramType := Type(Any)
+		if i < len(fnType.Parameters) {
+			paramType = fnType.Parameters[i]
+		}
+
+		c.declare(fnScope, param.Name, paramType, false, param.Loc)
+	}
+
+	enclosing := c.result
+	c.result = fnType.Return
+	result := checkBody(c, body, fnScope)
+	c.result = enclosing
+
+	if fnType.Return == Any || fnType.Return == Void {
+		return
+	}
+
+	if result != nil {
+		last := body[len(body)-1]
+		c.expectAssignable(result, fnType.Return, last.Span(), "function result")
+	} else if !alwaysReturns(body) {
+		c.errorAt(diagnostics.MissingReturn, span, "Function must return %s on every path", fnType.Return)
+	}
+}
+
+func checkForStmt(c *checker, stmt ast.ForStmt, s *scope) {
+	header := newScope(s)
+	if stmt.Init != nil {
+		checkStmt(c, stmt.Init, header)
+	}
+
+	if stmt.Condition != nil {
+		condition := checkExpr(c, stmt.Condition, header)
+		c.expectAssignable(condition, Boolean, stmt.Condition.Span(), "for condition")
+	}
+
+	if stmt.Post != nil {
+		checkExpr(c, stmt.Post, header)
+	}
+
+	checkBody(c, stmt.Body, newScope(header))
+}
+
+func checkReturnStmt(c *checker, stmt ast.ReturnStmt, s *scope) {
+	value := Type(Null)
+	if stmt.Value != nil {
+		value = checkExpr(c, stmt.Value, s)
+	}
+
+	switch {
+	case c.result == nil:
+		// Reported by the parser
+	case c.result == Void && stmt.Value != nil:
+		c.errorAt(diagnostics.TypeMismatch, stmt.Value.Span(), "Cannot return a value from a function returning void")
+	case c.result != Any && c.result != Void && stmt.Value == nil:
+		c.errorAt(diagnostics.MissingReturn, stmt.Loc, "Missing return value in function returning %s", c.result)
+	case stmt.Value != nil:
+		c.expectAssignable(value, c.result, stmt.Value.Span(), "return")
+	}
+}
+
+// alwaysReturns reports whether every path through body reaches a return
+// statement. A loop that runs forever and is never broken out of does not
+// reach the end of the body either.
+func alwaysReturns(body []ast.Stmt) bool {
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case ast.ReturnStmt:
+			return true
+		case ast.WhileStmt:
+			if isTrue(stmt.Condition) && !breaksOut(stmt.Body, stmt.Label, false) {
+				return true
+			}
+		case ast.ForStmt:
+			if (stmt.Condition == nil || isTrue(stmt.Condition)) && !breaksOut(stmt.Body, stmt.Label, false) {
+				return true
+			}
+		case ast.BlockStmt:
+			if alwaysReturns(stmt.Body) {
+				return true
+			}
+		case ast.IfStmt:
+			if stmt.Alternate != nil && alwaysReturns([]ast.Stmt{stmt.Consequent}) && alwaysReturns([]ast.Stmt{stmt.Alternate}) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// elementType gives the types of the two variables of a foreach over a
+// value of type t: the element and its index. Maps only exist as values
+// the checker types as any.
+func elementType(c *checker, t Type, span lexer.Span) (Type, Type) {
+	switch t := t.(type) {
+	case ArrayType:
+		return t.Element, Int
+	case PrimitiveType:
+		switch t {
+		case Any:
+			return Any, Any
+		case String:
+			return String, Int
+		}
+	}
+
+	c.errorAt(diagnostics.NotIterable, span, "Cannot iterate over %s", t)
+	return Any, Any
+}
+
+func isTrue(expr ast.Expr) bool {
+	literal, ok := expr.(ast.BooleanExpr)
+	return ok && literal.Value
+}
+
+// breaksOut reports whether a break in body leaves the loop labelled label
+// that body belongs to. nested is set inside the loops within body, which
+// a break without a label does not leave.
+func breaksOut(body []ast.Stmt, label string, nested bool) bool {
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case ast.BreakStmt:
+			if stmt.Label == "" && !nested || stmt.Label != "" && stmt.Label == label {
+				return true
+			}
+		case ast.BlockStmt:
+			if breaksOut(stmt.Body, label, nested) {
+				return true
+			}
+		case ast.IfStmt:
+			if breaksOut([]ast.Stmt{stmt.Consequent}, label, nested) || stmt.Alternate != nil && breaksOut([]ast.Stmt{stmt.Alternate}, label, nested) {
+				return true
+			}
+		case ast.WhileStmt:
+			if breaksOut(stmt.Body, label, true) {
+				return true
+			}
+		case ast.ForStmt:
+			if breaksOut(stmt.Body, label, true) {
+				return true
+			}
+		case ast.ForeachStmt:
+			if breaksOut(stmt.Body, label, true) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveClassMembers fills in the member types of class from the
+// declaration's annotations. Fields without an annotation are typed from
+// their default value later, in checkClassDeclStmt.
+func resolveClassMembers(c *checker, decl ast.ClassDeclarationStmt, class *ClassType, s *scope) {
+	members := class.members
+
+	for _, field := range decl.Fields {
+		members(field.IsStatic)[field.Name] = resolveType(c, field.Type, s)
+	}
+
+	for _, method := range decl.Methods {
+		members(method.IsStatic)[method.Name] = functionType(c, method.Parameters, method.ReturnType, s)
+	}
+
+	if decl.Constructor != nil {
+		constructor := functionType(c, decl.Constructor.Parameters, nil, s)
+		class.Constructor = &constructor
+		class.Members[decl.Constructor.Name] = constructor
+	}
+}
+
+func checkClassDeclStmt(c *checker, decl ast.ClassDeclarationStmt, s *scope) {
+	class, ok := c.hoisted[decl.Loc].(*ClassType)
+	if !ok {
+		return
+	}
+
+	staticScope := newScope(s)
+	instanceScope := newScope(s)
+	instanceScope.symbols["this"] = symbol{Type: InstanceType{Class: class}, Constant: true}
+
+	members := class.members
+
+	for _, field := range decl.Fields {
+		if field.DefaultValue == nil {
+			continue
+		}
+
+		value := checkExpr(c, field.DefaultValue, staticScope)
+		if field.Type == nil {
+			members(field.IsStatic)[field.Name] = inferred(value)
+			continue
+		}
+
+		c.expectAssignable(value, members(field.IsStatic)[field.Name], field.DefaultValue.Span(), "field "+field.Name)
+	}
+
+	if decl.Constructor != nil {
+		constructor := decl.Constructor
+		checkFunctionBody(c, constructor.Parameters, *class.Constructor, constructor.Body, constructor.Loc, instanceScope)
+	}
+
+	for _, method := range decl.Methods {
+		methodScope := instanceScope
+		if method.IsStatic {
+			methodScope = staticScope
+		}
+
+		fnType, _ := members(method.IsStatic)[method.Name].(FunctionType)
+		checkFunctionBody(c, method.Parameters, fnType, method.Body, method.Loc, methodScope)
+	}
+}
+
+func resolveStructMembers(c *checker, decl ast.StructDeclStmt, structType *StructType, s *scope) {
+	for _, name := range slices.Sorted(maps.Keys(decl.Properties)) {
+		property := decl.Properties[name]
+		if property.IsStatic {
+			structType.Static[name] = resolveType(c, property.Type, s)
+		} else {
+			structType.Fields[name] = resolveType(c, property.Type, s)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(decl.Methods)) {
+		method := decl.Methods[name]
+		fnType := functionType(c, method.Parameters, method.ReturnType, s)
+		if method.IsStatic {
+			structType.Static[name] = fnType
+		} else {
+			structType.Methods[name] = fnType
+		}
+	}
+}
+
+// checkStructDeclStmt checks method bodies. Instance methods see the
+// receiver as this.
+func checkStructDeclStmt(c *checker, decl ast.StructDeclStmt, s *scope) {
+	structType, ok := c.hoisted[decl.Loc].(*StructType)
+	if !ok {
+		return
+	}
+
+	staticScope := newScope(s)
+	instanceScope := newScope(s)
+	instanceScope.symbols["this"] = symbol{Type: structType, Constant: true}
+
+	for _, name := range slices.Sorted(maps.Keys(decl.Methods)) {
+		method := decl.Methods[name]
+		methodScope, fnType := instanceScope, structType.Methods[name]
+		if method.IsStatic {
+			methodScope, fnType = staticScope, structType.Static[name]
+		}
+
+		// A static property sharing the method's name has already been
+		// reported by the parser
+		if fnType, ok := fnType.(FunctionType); ok {
+			checkFunctionBody(c, method.Parameters, fnType, method.Body, method.Loc, methodScope)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package typecheck
+
+import (
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/lexer"
+	"custom_parser/src/parser"
+	"slices"
+	"testing"
+)
+
+// codes checks src and returns the code of every diagnostic, in order.
+func codes(t *testing.T, src string) []diagnostics.Code {
+	t.Helper()
+	tokens, _ := lexer.Tokenize(src)
+	program, list := parser.Parse(tokens)
+	if len(list) > 0 {
+		t.Fatalf("%s: %v", src, list[0])
+	}
+
+	var found []diagnostics.Code
+	for _, d := range Check(program) {
+		found = append(found, d.Code)
+	}
+
+	return found
+}
+
+func TestHoisting(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"greet(); fn greet() {}", nil},
+		{"let p = P{x: 1}; struct P { x: int; }", nil},
+		{"let c = new C(); class C { fn f() {} }", nil},
+		{"println(x); let x = 1;", []diagnostics.Code{diagnostics.UndefinedSymbol}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let x: int = 1; fn f(a: int): int { return a + x; } println(f(2));", nil},
+		{"let x: Foo;", []diagnostics.Code{diagnostics.UnknownType}},
+		{"println(y);", []diagnostics.Code{diagnostics.UndefinedSymbol}},
+		{`let x: int = "a";`, []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"let x = true - 1;", []diagnostics.Code{diagnostics.InvalidOperands}},
+		{"let x = 1; x();", []diagnostics.Code{diagnostics.NotCallable}},
+		{"fn f(a: int) {} f();", []diagnostics.Code{diagnostics.ArgumentCount}},
+		{"const x = 1; x = 2;", []diagnostics.Code{diagnostics.AssignToConstant}},
+		{"let x = 1; let x = 2;", []diagnostics.Code{diagnostics.Redeclared}},
+		{"struct P { x: int; } let p = P{x: 1}; println(p.y);", []diagnostics.Code{diagnostics.UnknownMember}},
+		{"foreach x in true {}", []diagnostics.Code{diagnostics.NotIterable}},
+		{"1 = 2;", []diagnostics.Code{diagnostics.InvalidAssignment}},
+		// Checking carries on after an error
+		{"let a: Foo; let b: int = true;", []diagnostics.Code{diagnostics.UnknownType, diagnostics.TypeMismatch}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestDuplicateTypes(t *testing.T) {
+	redeclared := []diagnostics.Code{diagnostics.Redeclared}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"struct A { x: int; } class A { }", redeclared},
+		{"class A { } struct A { x: int; }", redeclared},
+		{"class A { } class A { }", redeclared},
+		// The first declaration keeps the name
+		{"struct A { x: int; } struct A { y: string; } let a = A{x: 1};", redeclared},
+		{"class A { fn f(): int { return 1; } } struct A { x: int; } let a = new A(); let n: int = a.f();", redeclared},
+		{"struct int { }", redeclared},
+		// The members of a rejected declaration are still checked
+		{`struct A { } struct A { fn f(): int { return "a"; } }`, []diagnostics.Code{diagnostics.Redeclared, diagnostics.TypeMismatch}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSyntaxErrorsDoNotPanic(t *testing.T) {
+	// The parser reports the clash; checking the tree must not panic
+	tokens, _ := lexer.Tokenize("struct S { static f: int; static fn f() {} }")
+	program, list := parser.Parse(tokens)
+	if len(list) == 0 {
+		t.Fatal("expected a duplicate member")
+	}
+
+	Check(program)
+}
+
+func TestReturns(t *testing.T) {
+	missing := []diagnostics.Code{diagnostics.MissingReturn}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"fn f(c: boolean): int { if c { return 1; } else { return 2; } }", nil},
+		{"fn f(): int { { return 1; } }", nil},
+		{"fn neg(n: int): int { -n; }", nil},
+		{"fn f() { return; }", nil},
+		{"fn f(c: boolean): int { if c { return 1; } }", missing},
+		{"fn f(): int { let x = 1; }", missing},
+		{"fn f(): int { return; }", missing},
+		{`fn f(): int { "a"; }`, []diagnostics.Code{diagnostics.TypeMismatch}},
+		{`fn f(): int { return "a"; }`, []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"fn f(): void { return 1; }", []diagnostics.Code{diagnostics.TypeMismatch}},
+		// Each function is checked against its own return type
+		{"fn f(): int { let g = fn(): string { return \"a\"; }; return 1; }", nil},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	missing := []diagnostics.Code{diagnostics.MissingReturn}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let n = 3; for let i = 0; i < n; i += 1 { while i > 0 { break; } }", nil},
+		{"while 1 {}", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"for let i = 0; i; i += 1 {}", []diagnostics.Code{diagnostics.TypeMismatch}},
+		// The variables of a for header are scoped to the loop
+		{"for let i = 0; i < 3; i += 1 {} println(i);", []diagnostics.Code{diagnostics.UndefinedSymbol}},
+		// A loop that never ends needs no return after it
+		{"fn f(): int { while true {} }", nil},
+		{"fn f(): int { for let i = 0; ; i += 1 {} }", nil},
+		{"fn f(): int { while true { while true { break; } } }", nil},
+		{"fn f(): int { while true { break; } }", missing},
+		{"fn f(): int { outer: while true { while true { break outer; } } }", missing},
+		{"fn f(c: boolean): int { while c {} }", missing},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestForeachTypes(t *testing.T) {
+	mismatch := []diagnostics.Code{diagnostics.TypeMismatch}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{`foreach ch, i in "ab" { let c: string = ch; let n: int = i; }`, nil},
+		{`foreach ch in "ab" { let n: int = ch; }`, mismatch},
+		{"foreach x, i in [1.5] { let f: float = x; let n: int = i; }", nil},
+		{"foreach x in [1] { let s: string = x; }", mismatch},
+		{"foreach n, i in 0..3 { let m: int = n + i; }", nil},
+		// Module members are only known at runtime
+		{"import time; foreach key, value in time { let n: int = value; }", nil},
+		{"foreach x in 1.5 {}", []diagnostics.Code{diagnostics.NotIterable}},
+		{"foreach x in [1] {} println(x);", []diagnostics.Code{diagnostics.UndefinedSymbol}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
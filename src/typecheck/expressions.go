@@ -0,0 +1,395 @@
+package typecheck
+
+import (
+	"custom_parser/src/ast"
+	"custom_parser/src/diagnostics"
+	"custom_parser/src/lexer"
+	"maps"
+	"slices"
+)
+
+// checkExpr infers the type of expr, reporting any inconsistencies found
+// along the way.
+func checkExpr(c *checker, expr ast.Expr, s *scope) Type {
+	switch expr := expr.(type) {
+	case ast.NumberExpr:
+		if expr.Integral {
+			return Int
+		}
+
+		return Float
+	case ast.StringExpr:
+		return String
+	case ast.BooleanExpr:
+		return Boolean
+	case ast.NullExpr:
+		return Null
+	case ast.TemplateExpr:
+		// Any value can be interpolated
+		for _, part := range expr.Parts {
+			checkExpr(c, part, s)
+		}
+
+		return String
+	case ast.SymbolExpr:
+		sym, exists := s.lookup(expr.Value)
+		if !exists {
+			c.errorAt(diagnostics.UndefinedSymbol, expr.Loc, "Undefined symbol %s", expr.Value)
+			return Any
+		}
+
+		return sym.Type
+	case ast.PrefixExpr:
+		return checkPrefixExpr(c, expr, s)
+	case ast.BinaryExpr:
+		return checkBinaryExpr(c, expr, s)
+	case ast.ConditionalExpr:
+		return checkConditionalExpr(c, expr, s)
+	case ast.AssignmentExpr:
+		return checkAssignmentExpr(c, expr, s)
+	case ast.MemberExpr:
+		return memberType(c, checkExpr(c, expr.Member, s), expr.Property, expr.Loc)
+	case ast.ComputedExpr:
+		return checkComputedExpr(c, expr, s)
+	case ast.CallExpr:
+		return checkCallExpr(c, expr, s)
+	case ast.FunctionExpr:
+		fnType := functionType(c, expr.Parameters, expr.ReturnType, s)
+		checkFunctionBody(c, expr.Parameters, fnType, expr.Body, expr.Loc, s)
+		return fnType
+	case ast.ArrayLiteral:
+		return ArrayType{Element: checkElements(c, expr.Contents, nil, s)}
+	case ast.ArrayInstantiationExpr:
+		underlying := resolveType(c, expr.Underlying, s)
+		checkElements(c, expr.Contents, underlying, s)
+		return ArrayType{Element: underlying}
+	case ast.RangeExpr:
+		c.expectAssignable(checkExpr(c, expr.Lower, s), Int, expr.Lower.Span(), "range bound")
+		c.expectAssignable(checkExpr(c, expr.Upper, s), Int, expr.Upper.Span(), "range bound")
+		if expr.Step != nil {
+			c.expectAssignable(checkExpr(c, expr.Step, s), Int, expr.Step.Span(), "range step")
+		}
+		return ArrayType{Element: Int}
+	case ast.StructInstantiationExpr:
+		return checkStructInstantiationExpr(c, expr, s)
+	case ast.NewExpr:
+		return checkNewExpr(c, expr, s)
+	}
+
+	return Any
+}
+
+// checkElements checks every element against element, or against the type
+// of the first element when element is nil, and returns the element type.
+func checkElements(c *checker, contents []ast.Expr, element Type, s *scope) Type {
+	for _, content := range contents {
+		contentType := checkExpr(c, content, s)
+		if element == nil {
+			element = contentType
+			continue
+		}
+
+		c.expectAssignable(contentType, element, content.Span(), "array element")
+	}
+
+	if element == nil {
+		return Any
+	}
+
+	return element
+}
+
+func checkPrefixExpr(c *checker, expr ast.PrefixExpr, s *scope) Type {
+	right := checkExpr(c, expr.RightExpr, s)
+
+	switch expr.Operator.Kind {
+	case lexer.DASH:
+		c.expectOperands(expr.Operator, expr.Loc, right, Number)
+		if isNumeric(right) {
+			return right
+		}
+
+		return Any
+	case lexer.TILDE:
+		c.expectOperands(expr.Operator, expr.Loc, right, Int)
+		return Int
+	case lexer.NOT:
+		return Boolean
+	case lexer.TYPEOF:
+		return String
+	}
+
+	return Any
+}
+
+func checkBinaryExpr(c *checker, expr ast.BinaryExpr, s *scope) Type {
+	left := checkExpr(c, expr.Left, s)
+	right := checkExpr(c, expr.Right, s)
+
+	switch expr.Operator.Kind {
+	case lexer.AND, lexer.OR:
+		c.expectOperands(expr.Operator, expr.Loc, left, Boolean, right, Boolean)
+		return Boolean
+	case lexer.EQUALS, lexer.NOT_EQUALS:
+		if !assignable(left, right) && !assignable(right, left) {
+			c.errorAt(diagnostics.InvalidOperands, expr.Loc, "Cannot compare %s with %s", left, right)
+		}
+		return Boolean
+	case lexer.LESS, lexer.LESS_EQUALS, lexer.GREATER, lexer.GREATER_EQUALS:
+		if left == String && right == String {
+			return Boolean
+		}
+
+		c.expectOperands(expr.Operator, expr.Loc, left, Number, right, Number)
+		return Boolean
+	case lexer.AMPERSAND, lexer.PIPE, lexer.CARET, lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT:
+		c.expectOperands(expr.Operator, expr.Loc, left, Int, right, Int)
+		return Int
+	case lexer.PLUS:
+		if left == String || right == String {
+			return String
+		}
+	}
+
+	c.expectOperands(expr.Operator, expr.Loc, left, Number, right, Number)
+	return arithmeticType(left, right)
+}
+
+// checkConditionalExpr types a conditional as whichever branch type the
+// other converts to.
+func checkConditionalExpr(c *checker, expr ast.ConditionalExpr, s *scope) Type {
+	condition := checkExpr(c, expr.Condition, s)
+	c.expectAssignable(condition, Boolean, expr.Condition.Span(), "condition")
+	consequent := checkExpr(c, expr.Consequent, s)
+	alternate := checkExpr(c, expr.Alternate, s)
+
+	switch {
+	case consequent == Any || alternate == Any:
+		return Any
+	case isNumeric(consequent) && isNumeric(alternate):
+		return arithmeticType(consequent, alternate)
+	case assignable(alternate, consequent):
+		return consequent
+	case assignable(consequent, alternate):
+		return alternate
+	}
+
+	c.errorAt(diagnostics.TypeMismatch, expr.Loc, "The branches of a conditional have different types %s and %s", consequent, alternate)
+	return Any
+}
+
+// expectOperands takes pairs of (actual, expected) operand types and
+// reports a single error if any pair does not match.
+func (c *checker) expectOperands(operator lexer.Token, span lexer.Span, pairs ...Type) {
+	for i := 0; i < len(pairs); i += 2 {
+		if !assignable(pairs[i], pairs[i+1]) {
+			if len(pairs) == 2 {
+				c.errorAt(diagnostics.InvalidOperands, span, "Operator %s is not defined for %s", operator.Value, pairs[0])
+			} else {
+				c.errorAt(diagnostics.InvalidOperands, span, "Operator %s is not defined for %s and %s", operator.Value, pairs[0], pairs[2])
+			}
+
+			return
+		}
+	}
+}
+
+func checkAssignmentExpr(c *checker, expr ast.AssignmentExpr, s *scope) Type {
+	value := checkExpr(c, expr.Value, s)
+
+	var target Type
+	switch assignee := expr.Assignee.(type) {
+	case ast.SymbolExpr:
+		sym, exists := s.lookup(assignee.Value)
+		if !exists {
+			c.errorAt(diagnostics.UndefinedSymbol, assignee.Loc, "Undefined symbol %s", assignee.Value)
+			return value
+		}
+
+		if sym.Constant {
+			c.errorAt(diagnostics.AssignToConstant, expr.Loc, "Cannot assign to constant %s", assignee.Value)
+		}
+
+		target = sym.Type
+	case ast.MemberExpr, ast.ComputedExpr:
+		target = checkExpr(c, assignee, s)
+	default:
+		c.errorAt(diagnostics.InvalidAssignment, expr.Assignee.Span(), "Invalid assignment target")
+		return value
+	}
+
+	if expr.Operator.Kind != lexer.ASSIGNMENT {
+		if expr.Operator.Kind == lexer.PLUS_EQUALS && target == String {
+			return String
+		}
+
+		c.expectOperands(expr.Operator, expr.Loc, target, Number, value, Number)
+		if isNumeric(target) && isNumeric(value) {
+			c.expectAssignable(arithmeticType(target, value), target, expr.Loc, "assignment")
+		}
+
+		return target
+	}
+
+	c.expectAssignable(value, target, expr.Value.Span(), "assignment")
+	return target
+}
+
+func memberType(c *checker, object Type, property string, span lexer.Span) Type {
+	switch object := object.(type) {
+	case *StructType:
+		if field, exists := object.Fields[property]; exists {
+			return field
+		}
+
+		if method, exists := object.Methods[property]; exists {
+			return method
+		}
+	case StructDefinition:
+		if member, exists := object.Struct.Static[property]; exists {
+			return member
+		}
+	case InstanceType:
+		if member, exists := object.Class.Members[property]; exists {
+			return member
+		}
+	case *ClassType:
+		if member, exists := object.Static[property]; exists {
+			return member
+		}
+	case ArrayType:
+		switch property {
+		case "length":
+			return Int
+		case "push":
+			return FunctionType{Parameters: []Type{object.Element}, Variadic: true, Return: Int}
+		}
+	case PrimitiveType:
+		if object == Any {
+			return Any
+		}
+
+		if object == String && property == "length" {
+			return Int
+		}
+	}
+
+	c.errorAt(diagnostics.UnknownMember, span, "%s has no member %s", object, property)
+	return Any
+}
+
+func checkComputedExpr(c *checker, expr ast.ComputedExpr, s *scope) Type {
+	object := checkExpr(c, expr.Member, s)
+	index := checkExpr(c, expr.Property, s)
+
+	switch object := object.(type) {
+	case ArrayType:
+		c.expectAssignable(index, Int, expr.Property.Span(), "array index")
+		return object.Element
+	case PrimitiveType:
+		if object == Any {
+			return Any
+		}
+
+		if object == String {
+			c.expectAssignable(index, Int, expr.Property.Span(), "string index")
+			return String
+		}
+	}
+
+	c.errorAt(diagnostics.InvalidOperands, expr.Loc, "Cannot index %s", object)
+	return Any
+}
+
+func checkCallExpr(c *checker, expr ast.CallExpr, s *scope) Type {
+	callee := checkExpr(c, expr.Method, s)
+	args := make([]Type, len(expr.Arguments))
+	for i, argument := range expr.Arguments {
+		args[i] = checkExpr(c, argument, s)
+	}
+
+	if callee == Any {
+		return Any
+	}
+
+	fnType, ok := callee.(FunctionType)
+	if !ok {
+		c.errorAt(diagnostics.NotCallable, expr.Method.Span(), "%s is not callable", callee)
+		return Any
+	}
+
+	checkArguments(c, fnType, args, expr)
+	return fnType.Return
+}
+
+func checkArguments(c *checker, fnType FunctionType, args []Type, call ast.CallExpr) {
+	if fnType.Variadic {
+		for i, arg := range args {
+			c.expectAssignable(arg, fnType.Parameters[0], call.Arguments[i].Span(), "argument")
+		}
+
+		return
+	}
+
+	if len(args) != len(fnType.Parameters) {
+		c.errorAt(diagnostics.ArgumentCount, call.Loc, "Expected %d arguments but received %d", len(fnType.Parameters), len(args))
+		return
+	}
+
+	for i, arg := range args {
+		c.expectAssignable(arg, fnType.Parameters[i], call.Arguments[i].Span(), "argument")
+	}
+}
+
+func checkStructInstantiationExpr(c *checker, expr ast.StructInstantiationExpr, s *scope) Type {
+	resolved, exists := s.lookupType(expr.StructName)
+	structType, isStruct := resolved.(*StructType)
+	if !exists || !isStruct {
+		c.errorAt(diagnostics.UnknownType, expr.Loc, "Unknown struct %s", expr.StructName)
+		for _, name := range slices.Sorted(maps.Keys(expr.Properties)) {
+			checkExpr(c, expr.Properties[name], s)
+		}
+
+		return Any
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(expr.Properties)) {
+		value := expr.Properties[name]
+		valueType := checkExpr(c, value, s)
+		field, exists := structType.Fields[name]
+		if !exists {
+			c.errorAt(diagnostics.UnknownMember, value.Span(), "%s has no field %s", structType, name)
+			continue
+		}
+
+		c.expectAssignable(valueType, field, value.Span(), "field "+name)
+	}
+
+	return structType
+}
+
+func checkNewExpr(c *checker, expr ast.NewExpr, s *scope) Type {
+	callee := checkExpr(c, expr.Instantiation.Method, s)
+	args := make([]Type, len(expr.Instantiation.Arguments))
+	for i, argument := range expr.Instantiation.Arguments {
+		args[i] = checkExpr(c, argument, s)
+	}
+
+	if callee == Any {
+		return Any
+	}
+
+	class, ok := callee.(*ClassType)
+	if !ok {
+		c.errorAt(diagnostics.NotCallable, expr.Instantiation.Method.Span(), "Cannot instantiate %s", callee)
+		return Any
+	}
+
+	constructor := FunctionType{Return: Void}
+	if class.Constructor != nil {
+		constructor = *class.Constructor
+	}
+
+	checkArguments(c, constructor, args, expr.Instantiation)
+	return InstanceType{Class: class}
+}
@@ -0,0 +1,110 @@
+package typecheck
+
+import (
+	"custom_parser/src/diagnostics"
+	"slices"
+	"testing"
+)
+
+func TestConstructors(t *testing.T) {
+	class := "class C { let n: int; fn mount(n: int) { this.n = n; } } "
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{class + "let c = new C(1);", nil},
+		{class + "let c = new C();", []diagnostics.Code{diagnostics.ArgumentCount}},
+		{class + `let c = new C("a");`, []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"class E {} let e = new E(1);", []diagnostics.Code{diagnostics.ArgumentCount}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestArithmeticTypes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let x: int = 7 / 2 + 7 % 2;", nil},
+		{"let x: float = 7 / 2.0;", nil},
+		{"let x: int = 1 + 1.5;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"let x: int = 6 & 3 | 1 << 2;", nil},
+		{"let x = 1.5 & 1;", []diagnostics.Code{diagnostics.InvalidOperands}},
+		{"let x = true - 1;", []diagnostics.Code{diagnostics.InvalidOperands}},
+		{`let x: string = "a" + 1;`, nil},
+		{"let x: boolean = 1 < 1.5;", nil},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestTemplateTypes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let n = 1; let s: string = `n=${n} ${[n]}`;", nil},
+		{"let s: int = `a`;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		// Interpolated expressions are checked like any other
+		{"let s = `${missing}`;", []diagnostics.Code{diagnostics.UndefinedSymbol}},
+		{"let s = `${`${true - 1}`}`;", []diagnostics.Code{diagnostics.InvalidOperands}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestRangeTypes(t *testing.T) {
+	mismatch := []diagnostics.Code{diagnostics.TypeMismatch}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let xs: []int = 0..3;", nil},
+		{"let n = 5; let xs: []int = 0..=n - 1 step 2;", nil},
+		{"let xs: []string = 0..3;", mismatch},
+		{"let xs = 0..1.5;", mismatch},
+		{`let xs = "a"..3;`, mismatch},
+		{"let xs = 0..3 step 0.5;", mismatch},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestConditionalTypes(t *testing.T) {
+	mismatch := []diagnostics.Code{diagnostics.TypeMismatch}
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{`let c = true; let s: string = c ? "a" : "b";`, nil},
+		{"let c = true; let x: float = c ? 1 : 2.5;", nil},
+		{"let c = true; let x: int = c ? 1 : 2.5;", mismatch},
+		{`let c = true; let s: string = c ? "a" : null;`, nil},
+		{`let c = true; let x = c ? 1 : "a";`, mismatch},
+		{"let x = 1 ? 2 : 3;", mismatch},
+		{`let c = true; let s: string = c ? "a" : c ? "b" : "c";`, nil},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package typecheck
+
+type symbol struct {
+	Type     Type
+	Constant bool
+}
+
+// scope holds the values and the named types visible in one block.
+type scope struct {
+	parent  *scope
+	symbols map[string]symbol
+	types   map[string]Type
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:  parent,
+		symbols: map[string]symbol{},
+		types:   map[string]Type{},
+	}
+}
+
+func (s *scope) lookup(name string) (symbol, bool) {
+	for current := s; current != nil; current = current.parent {
+		if sym, exists := current.symbols[name]; exists {
+			return sym, true
+		}
+	}
+
+	return symbol{}, false
+}
+
+func (s *scope) lookupType(name string) (Type, bool) {
+	for current := s; current != nil; current = current.parent {
+		if t, exists := current.types[name]; exists {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,175 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the checker's view of a type, resolved from an ast.Type
+// annotation or inferred from an expression.
+type Type interface {
+	String() string
+}
+
+type PrimitiveType struct {
+	Name string
+}
+
+func (t PrimitiveType) String() string { return t.Name }
+
+var (
+	Int   = PrimitiveType{Name: "int"}   // exact 64-bit integers
+	Float = PrimitiveType{Name: "float"} // 64-bit floating point
+
+	// Number is an int or a float, decided at run time. Ints and floats
+	// convert to it implicitly. Like a float it converts to float but not
+	// to int, since nothing checks at run time that the value is whole.
+	Number = PrimitiveType{Name: "number"}
+
+	String  = PrimitiveType{Name: "string"}
+	Boolean = PrimitiveType{Name: "boolean"}
+	Null    = PrimitiveType{Name: "null"}
+	Void    = PrimitiveType{Name: "void"}
+
+	// Any is compatible with every type. It is given to values the checker
+	// cannot see into, such as imported modules.
+	Any = PrimitiveType{Name: "any"}
+)
+
+type ArrayType struct {
+	Element Type
+}
+
+func (t ArrayType) String() string { return "[]" + t.Element.String() }
+
+type FunctionType struct {
+	Parameters []Type
+	Variadic   bool // accepts any number of arguments of Parameters[0]
+	Return     Type
+}
+
+func (t FunctionType) String() string {
+	params := make([]string, len(t.Parameters))
+	for i, param := range t.Parameters {
+		params[i] = param.String()
+	}
+
+	if t.Variadic {
+		params[0] = "..." + params[0]
+	}
+
+	return fmt.Sprintf("fn(%s): %s", strings.Join(params, ", "), t.Return)
+}
+
+// StructType is the type of struct instances.
+type StructType struct {
+	Name    string
+	Fields  map[string]Type
+	Methods map[string]Type
+	Static  map[string]Type // static properties and methods
+}
+
+func (t *StructType) String() string { return t.Name }
+
+// StructDefinition is the type of a struct's name used as a value, which
+// gives access to its static members.
+type StructDefinition struct {
+	Struct *StructType
+}
+
+func (t StructDefinition) String() string { return "struct " + t.Struct.Name }
+
+// ClassType is the type of the class itself, as used with new and for
+// static member access. Values created from it have an InstanceType.
+type ClassType struct {
+	Name        string
+	Members     map[string]Type // instance fields and methods
+	Static      map[string]Type
+	Constructor *FunctionType
+}
+
+func (t *ClassType) String() string { return "class " + t.Name }
+
+// members returns the static or the instance member table.
+func (t *ClassType) members(isStatic bool) map[string]Type {
+	if isStatic {
+		return t.Static
+	}
+
+	return t.Members
+}
+
+type InstanceType struct {
+	Class *ClassType
+}
+
+func (t InstanceType) String() string { return t.Class.Name }
+
+// assignable reports whether a value of type from may be stored where type
+// to is expected.
+func assignable(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+
+	// Every type is nullable: fields and variables start out as null
+	if from == Null {
+		return true
+	}
+
+	if isNumeric(from) && isNumeric(to) {
+		// Only an int converts to int; floats and numbers may not be whole
+		return to != Int || from == Int
+	}
+
+	switch to := to.(type) {
+	case ArrayType:
+		from, ok := from.(ArrayType)
+		return ok && assignable(from.Element, to.Element)
+	case FunctionType:
+		from, ok := from.(FunctionType)
+		if !ok || from.Variadic != to.Variadic || len(from.Parameters) != len(to.Parameters) {
+			return false
+		}
+
+		for i := range to.Parameters {
+			if !assignable(to.Parameters[i], from.Parameters[i]) {
+				return false
+			}
+		}
+
+		return assignable(from.Return, to.Return)
+	}
+
+	return from == to
+}
+
+func isNumeric(t Type) bool {
+	return t == Int || t == Float || t == Number
+}
+
+// arithmeticType is the type of an arithmetic operation on two numeric
+// operands: int only when both are ints.
+func arithmeticType(left, right Type) Type {
+	switch {
+	case left == Any || right == Any:
+		return Any
+	case left == Number || right == Number:
+		return Number
+	case left == Float || right == Float:
+		return Float
+	default:
+		return Int
+	}
+}
+
+// inferred is the type of a variable or field declared without an
+// annotation. A null initial value says nothing about the values that
+// follow it, so such a variable may hold anything.
+func inferred(value Type) Type {
+	if value == Null {
+		return Any
+	}
+
+	return value
+}
@@ -0,0 +1,58 @@
+package typecheck
+
+import (
+	"custom_parser/src/diagnostics"
+	"slices"
+	"testing"
+)
+
+func TestNumericConversions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"let x: float = 1;", nil},
+		{"let x: number = 1;", nil},
+		{"let x: number = 1.5;", nil},
+		{"let n: number = 1; let x: float = n;", nil},
+		{"let x: float = 1.5; x = 2;", nil},
+		{"fn half(v: float): float { return v / 2; } half(3);", nil},
+		{"fn f(): float { return 1; }", nil},
+		{"struct P { x: float; } let p = P{x: 1}; p.x = 2;", nil},
+		{"let x: int = 1.5;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"let n: number = 1; let x: int = n;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"fn f(n: number): number { return n; } let x: int = f(1.5);", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"fn f(n: int): int { return n; } let n: number = 1; f(n);", []diagnostics.Code{diagnostics.TypeMismatch}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestNullability(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diagnostics.Code
+	}{
+		{"const ok: boolean = true; let no = false; let b: boolean = !ok || no;", nil},
+		{"let x: int = null; let s: string = null; let xs: []int = null;", nil},
+		{"let x: int = 1; x = null; let same: boolean = x == null;", nil},
+		{"fn f(): int { return null; }", nil},
+		// A variable starting out as null may hold anything later
+		{`let x = null; x = 1; x = "a";`, nil},
+		{"let b: boolean = null == 1;", nil},
+		{"let n: int = true;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"let b: boolean = 1;", []diagnostics.Code{diagnostics.TypeMismatch}},
+		{"let b = true && 1;", []diagnostics.Code{diagnostics.InvalidOperands}},
+		{`let b = true == "a";`, []diagnostics.Code{diagnostics.InvalidOperands}},
+	}
+
+	for _, test := range tests {
+		if got := codes(t, test.src); !slices.Equal(got, test.want) {
+			t.Errorf("%s\ngot  %v\nwant %v", test.src, got, test.want)
+		}
+	}
+}